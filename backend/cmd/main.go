@@ -1,102 +1,248 @@
 package main
 
 import (
-	"cv-backend/internal/handlers"
-	"cv-backend/internal/middleware"
+	"context"
+	"cv-backend/internal/auth"
+	"cv-backend/internal/config"
+	"cv-backend/internal/health"
+	"cv-backend/internal/logging"
+	"cv-backend/internal/metrics"
+	"cv-backend/internal/server"
 	"cv-backend/internal/storage"
+	"cv-backend/internal/tracing"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
 )
 
 func main() {
+	// Log JSON at info level until the configured level is known
+	logging.Setup(os.Stdout, "info")
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+		slog.Info("no .env file found, using system environment variables")
+	}
+
+	// Load and validate all settings before touching anything else
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	logging.Setup(os.Stdout, cfg.LogLevel)
+	auth.Configure(cfg.Auth.JWTSecret, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+
+	// Export spans of requests and queries as configured by TRACING_EXPORTER
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+
+	// "migrate" manages the database schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+
+	// Initialize blob storage for CV file content
+	if err := storage.InitBlobStore(cfg); err != nil {
+		fatal("failed to initialize blob storage", err)
+	}
+
+	// SIGINT and SIGTERM (docker stop, redeploys) start a graceful shutdown
+	// once startup is complete
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Connect to the database; the schema is migrated once the server listens
+	if err := storage.ConnectDB(cfg); err != nil {
+		fatal("failed to connect to database", err)
+	}
+
+	db, blobs := storage.GetDB(), storage.GetBlobStore()
+	stores := storage.NewStores(db, blobs)
+
+	// Expose connection pool statistics at /metrics
+	if sqlDB, err := db.DB(); err == nil {
+		metrics.RegisterDBStats(sqlDB)
+	}
+
+	// Readiness checks behind /readyz, which reports not ready until the
+	// startup migrations are done
+	checker := health.NewChecker(cfg.ReadinessTimeout,
+		health.Check{Name: "database", Run: func(ctx context.Context) error { return storage.PingDB(ctx, db) }},
+		health.Check{Name: "blob_store", Run: blobs.Ping},
+	)
+	checker.SetMigrating(true)
+
+	gin.SetMode(cfg.Mode)
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		slog.Debug("route registered", "method", method, "path", path, "handler", handler)
+	}
+
+	// Initialize Gin router with all routes
+	router, err := server.NewRouter(cfg, stores, checker)
+	if err != nil {
+		fatal("failed to set up routes", err)
+	}
+
+	// Listen before migrating so that the probes answer during long
+	// migrations; the API routes answer 503 until startup is complete
+	listener, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		fatal("failed to start server", err)
+	}
+	srv := &http.Server{
+		Handler:      router,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
 	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(listener) }()
+	slog.Info("server listening", "port", cfg.Port)
 
-	// Initialize database
-	if err := storage.InitDB(); err != nil {
-		log.Fatal("Failed to initialize database:", err)
+	if err := storage.MigrateOnStartup(cfg); err != nil {
+		fatal("failed to migrate database", err)
+	}
+	slog.Info("database initialized")
+
+	// Move CV content from the legacy file_data column into blob storage
+	if err := storage.NewCVStorage(db, blobs).MigrateLegacyFileData(context.Background()); err != nil {
+		fatal("failed to migrate legacy CV data", err)
 	}
-	log.Println("✅ Database initialized")
 
 	// Initialize default user
-	userStorage := storage.NewUserStorage()
-	adminUsername := os.Getenv("ADMIN_USERNAME")
-	adminPassword := os.Getenv("ADMIN_PASSWORD")
-	if adminUsername == "" {
-		adminUsername = "admin"
-	}
-	if adminPassword == "" {
-		adminPassword = "cvadmin2024"
-	}
-	
-	if err := userStorage.InitializeDefaultUser(adminUsername, adminPassword); err != nil {
-		log.Printf("Warning: Failed to initialize default user: %v", err)
-	}
-
-	// Set Gin mode
-	if os.Getenv("GIN_MODE") == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	// Initialize Gin router
-	router := gin.Default()
-
-	// Apply CORS middleware
-	router.Use(middleware.CORSMiddleware())
-
-	// Initialize handlers
-	authHandler := handlers.NewAuthHandler()
-	cvHandler := handlers.NewCVHandler()
-
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "service": "cv-backend"})
-	})
-
-	// Public routes
-	api := router.Group("/api")
-	{
-		// Authentication
-		api.POST("/login", authHandler.Login)
-		
-		// CV download and viewing (public)
-		api.GET("/download-cv", cvHandler.DownloadCV)
-		api.GET("/view-cv", cvHandler.ViewCV)
-	}
-
-	// Protected routes (require authentication)
-	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware())
-	{
-		// Auth verification
-		protected.GET("/verify", authHandler.VerifyToken)
-		
-		// Password management
-		protected.PUT("/change-password", authHandler.ChangePassword)
-		
-		// CV management
-		protected.POST("/upload-cv", cvHandler.UploadCV)
-		protected.GET("/cv-info", cvHandler.GetCVInfo)
-		protected.GET("/cv-stats", cvHandler.GetStats)
-		protected.DELETE("/cv", cvHandler.DeleteCV)
-	}
-
-	// Get port from environment or default to 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Printf("🚀 Server starting on port %s", port)
-	log.Printf("💾 File storage: data/ directory")
-	log.Printf("🔐 Admin username: %s", adminUsername)
-	
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
-	}
-}
\ No newline at end of file
+	if err := stores.Users.InitializeDefaultUser(context.Background(), cfg.Auth.AdminUsername, cfg.Auth.AdminPassword); err != nil {
+		slog.Warn("failed to initialize default user", "error", err)
+	}
+
+	checker.SetMigrating(false)
+	slog.Info("ready to serve requests")
+
+	select {
+	case err := <-serveErr:
+		fatal("server stopped", err)
+	case <-ctx.Done():
+		stop()
+	}
+
+	shutdown(cfg, srv, checker, db, shutdownTracing)
+}
+
+// shutdown stops taking traffic and drains in-flight requests up to
+// SHUTDOWN_TIMEOUT. /readyz fails for SHUTDOWN_DELAY first so that load
+// balancers stop routing new requests here. Pending spans are flushed and the
+// database pool is closed last.
+func shutdown(cfg *config.Config, srv *http.Server, checker *health.Checker, db *gorm.DB, shutdownTracing func(context.Context) error) {
+	checker.SetShuttingDown()
+	slog.Info("shutting down, no longer ready")
+	time.Sleep(cfg.HTTP.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("requests still in flight were cut off", "timeout", cfg.HTTP.ShutdownTimeout.String(), "error", err)
+		srv.Close()
+	} else {
+		slog.Info("in-flight requests drained")
+	}
+
+	// Draining may have used up the deadline, so flushing gets its own
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Warn("failed to close database", "error", err)
+		}
+	}
+	slog.Info("server stopped")
+}
+
+// fatal logs an error that prevents the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// runMigrate implements "migrate status", "migrate up" and "migrate down [n]"
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: migrate status | up | down [steps]")
+	}
+
+	if err := storage.ConnectDB(cfg); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	migrator, err := storage.NewMigrator()
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal("Failed to get migration status:", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Modified {
+				state += " (changed since)"
+			}
+			if status.Unknown {
+				state += " (unknown to this build)"
+			}
+			fmt.Printf("%04d %-40s %s\n", status.Version, status.Name, state)
+		}
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal("Steps must be a positive number")
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("Rolled back %04d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("No applied migrations")
+		}
+	default:
+		log.Fatalf("Unknown migrate command %q (use status, up or down)", args[0])
+	}
+}
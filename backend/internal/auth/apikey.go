@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognise
+const APIKeyPrefix = "cvk_"
+
+// apiKeyDisplayLength is how many characters of a key are stored in clear
+// text to tell keys apart
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey returns a new random API key and the prefix shown to
+// identify it. Only its hash (see HashAPIKey) is stored.
+func GenerateAPIKey() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return key, key[:apiKeyDisplayLength], nil
+}
+
+// HashAPIKey returns the value stored in the database for an API key
+func HashAPIKey(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
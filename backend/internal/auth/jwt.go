@@ -1,8 +1,12 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"os"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -10,19 +14,63 @@ import (
 
 type Claims struct {
 	Username string `json:"username"`
+	// TokenVersion must match the user's current token version; it is bumped
+	// on password changes and "log out all sessions"
+	TokenVersion int `json:"tv"`
+	// Purpose is empty for access tokens and "2fa" for the challenge token
+	// that links the two login steps
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for the given username
-func GenerateToken(username string) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return "", errors.New("JWT_SECRET not set")
+const (
+	purposeTwoFactor      = "2fa"
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
+// Signing key and token lifetimes, set once at startup by Configure
+var (
+	jwtSecret       []byte
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Configure sets the JWT signing secret and the token lifetimes
+func Configure(secret string, accessTTL, refreshTTL time.Duration) {
+	jwtSecret = []byte(secret)
+	accessTokenTTL = accessTTL
+	refreshTokenTTL = refreshTTL
+}
+
+// AccessTokenTTL returns how long access tokens are valid
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+// RefreshTokenTTL returns how long refresh tokens are valid
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenTTL
+}
+
+// signingKey returns the JWT secret, or an error if Configure was not called
+func signingKey() ([]byte, error) {
+	if len(jwtSecret) == 0 {
+		return nil, errors.New("JWT secret not configured")
 	}
+	return jwtSecret, nil
+}
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateToken generates a short-lived JWT access token for the given username
+func GenerateToken(username string, tokenVersion int) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	expirationTime := time.Now().Add(AccessTokenTTL())
 	claims := &Claims{
-		Username: username,
+		Username:     username,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -31,7 +79,7 @@ func GenerateToken(username string) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		return "", err
 	}
@@ -39,11 +87,72 @@ func GenerateToken(username string) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// GenerateChallengeToken issues the token that proves the password step of
+// a two-factor login succeeded. It is not accepted as an access token.
+func GenerateChallengeToken(username string, tokenVersion int) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		Username:     username,
+		TokenVersion: tokenVersion,
+		Purpose:      purposeTwoFactor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(twoFactorChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "cv-backend",
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// ValidateChallengeToken validates a two-factor challenge token and returns its claims
+func ValidateChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purposeTwoFactor {
+		return nil, errors.New("not a challenge token")
+	}
+	return claims, nil
+}
+
+// GenerateRefreshToken returns a random opaque refresh token. Only its hash
+// (see HashRefreshToken) is stored.
+func GenerateRefreshToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// HashRefreshToken returns the value stored in the database for a refresh token
+func HashRefreshToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+// ValidateToken validates a JWT access token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, errors.New("JWT_SECRET not set")
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("not an access token")
+	}
+	return claims, nil
+}
+
+func parseToken(tokenString string) (*Claims, error) {
+	key, err := signingKey()
+	if err != nil {
+		return nil, err
 	}
 
 	claims := &Claims{}
@@ -51,7 +160,7 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(jwtSecret), nil
+		return key, nil
 	})
 
 	if err != nil {
@@ -63,4 +172,4 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	return claims, nil
-}
\ No newline at end of file
+}
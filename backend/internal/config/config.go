@@ -0,0 +1,570 @@
+// Package config loads the server settings and validates them at startup.
+//
+// Every setting has one name, e.g. JWT_SECRET, used both as environment
+// variable and as key in the optional YAML or TOML file named by
+// CONFIG_FILE. Environment variables take precedence over the file, which
+// takes precedence over the defaults. Any setting can instead be read from a
+// file named by its _FILE variant (JWT_SECRET_FILE=/run/secrets/jwt), which
+// keeps secrets out of the environment.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultAdminPassword is the password of the first owner outside release
+// mode when ADMIN_PASSWORD is not set
+const DefaultAdminPassword = "cvadmin2024"
+
+// minJWTSecretLength is the shortest accepted JWT secret in bytes (256 bits)
+const minJWTSecretLength = 32
+
+var languageTagPattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// Config holds all settings of the server
+type Config struct {
+	Mode           string // gin mode: debug, release or test
+	Port           string
+	LogLevel       string   // debug, info, warn or error
+	TrustedProxies []string // reverse proxies allowed to set X-Forwarded-For
+
+	HTTP     HTTPConfig
+	Database DatabaseConfig
+	Blob     BlobConfig
+	Auth     AuthConfig
+	CV       CVConfig
+	Tracing  TracingConfig
+	Scan     ScanConfig
+
+	AnalyticsSalt string // keyed hash of visitor IPs; random per process if empty
+	MetricsToken  string // bearer token required to scrape /metrics, if set
+
+	ReadinessTimeout time.Duration // limit for each /readyz dependency check
+
+	sources map[string]string // where each setting came from
+}
+
+// HTTPConfig holds the server timeouts; zero read, write and idle timeouts
+// mean no limit
+type HTTPConfig struct {
+	ReadTimeout     time.Duration // reading a whole request, including uploads
+	WriteTimeout    time.Duration // writing a whole response, including downloads
+	IdleTimeout     time.Duration // keep-alive connections between requests
+	ShutdownDelay   time.Duration // serving with /readyz failing before draining
+	ShutdownTimeout time.Duration // draining in-flight requests
+}
+
+// DatabaseConfig selects the database and how its schema is migrated
+type DatabaseConfig struct {
+	URL     string // postgres://..., sqlite://path or a Postgres keyword DSN
+	Migrate string // auto or check
+}
+
+// BlobConfig selects where CV file content is stored
+type BlobConfig struct {
+	Backend           string // fs or s3
+	Dir               string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool
+}
+
+// AuthConfig holds the token and account settings
+type AuthConfig struct {
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	TOTPIssuer      string
+	AdminUsername   string // first owner, created when there are no users
+	AdminPassword   string
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported
+type TracingConfig struct {
+	Exporter     string  // none, otlp or stdout
+	OTLPEndpoint string  // OTLP/HTTP collector URL, e.g. http://otel-collector:4318
+	SampleRatio  float64 // share of new traces that are recorded, 0 to 1
+}
+
+// ScanConfig selects the malware scanner for uploads
+type ScanConfig struct {
+	ClamdAddress string        // tcp://host:port or unix:///path/to/clamd.sock; empty disables scanning
+	FailMode     string        // closed rejects uploads while the scanner fails, open accepts them unscanned
+	Timeout      time.Duration // limit for scanning one file
+}
+
+// CVConfig holds the settings of the served and generated CV
+type CVConfig struct {
+	DefaultLanguage string
+	OwnerName       string
+	OwnerHeadline   string
+}
+
+// setting binds a name to a field of the Config
+type setting struct {
+	key    string
+	target interface{}         // *string, *bool, *time.Duration, *float64 or *[]string
+	redact func(string) string // hides secrets in the configuration dump
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "GIN_MODE", target: &c.Mode},
+		{key: "PORT", target: &c.Port},
+		{key: "LOG_LEVEL", target: &c.LogLevel},
+		{key: "TRUSTED_PROXIES", target: &c.TrustedProxies},
+		{key: "HTTP_READ_TIMEOUT", target: &c.HTTP.ReadTimeout},
+		{key: "HTTP_WRITE_TIMEOUT", target: &c.HTTP.WriteTimeout},
+		{key: "HTTP_IDLE_TIMEOUT", target: &c.HTTP.IdleTimeout},
+		{key: "SHUTDOWN_DELAY", target: &c.HTTP.ShutdownDelay},
+		{key: "SHUTDOWN_TIMEOUT", target: &c.HTTP.ShutdownTimeout},
+		{key: "DATABASE_URL", target: &c.Database.URL, redact: redactURL},
+		{key: "DB_MIGRATE", target: &c.Database.Migrate},
+		{key: "BLOB_BACKEND", target: &c.Blob.Backend},
+		{key: "BLOB_DIR", target: &c.Blob.Dir},
+		{key: "S3_ENDPOINT", target: &c.Blob.S3Endpoint},
+		{key: "S3_REGION", target: &c.Blob.S3Region},
+		{key: "S3_BUCKET", target: &c.Blob.S3Bucket},
+		{key: "S3_ACCESS_KEY_ID", target: &c.Blob.S3AccessKeyID},
+		{key: "S3_SECRET_ACCESS_KEY", target: &c.Blob.S3SecretAccessKey, redact: maskSecret},
+		{key: "S3_PATH_STYLE", target: &c.Blob.S3PathStyle},
+		{key: "JWT_SECRET", target: &c.Auth.JWTSecret, redact: maskSecret},
+		{key: "ACCESS_TOKEN_TTL", target: &c.Auth.AccessTokenTTL},
+		{key: "REFRESH_TOKEN_TTL", target: &c.Auth.RefreshTokenTTL},
+		{key: "TOTP_ISSUER", target: &c.Auth.TOTPIssuer},
+		{key: "ADMIN_USERNAME", target: &c.Auth.AdminUsername},
+		{key: "ADMIN_PASSWORD", target: &c.Auth.AdminPassword, redact: maskSecret},
+		{key: "CV_DEFAULT_LANGUAGE", target: &c.CV.DefaultLanguage},
+		{key: "CV_OWNER_NAME", target: &c.CV.OwnerName},
+		{key: "CV_OWNER_HEADLINE", target: &c.CV.OwnerHeadline},
+		{key: "ANALYTICS_SALT", target: &c.AnalyticsSalt, redact: maskSecret},
+		{key: "METRICS_TOKEN", target: &c.MetricsToken, redact: maskSecret},
+		{key: "READINESS_TIMEOUT", target: &c.ReadinessTimeout},
+		{key: "TRACING_EXPORTER", target: &c.Tracing.Exporter},
+		{key: "OTLP_ENDPOINT", target: &c.Tracing.OTLPEndpoint, redact: redactURL},
+		{key: "TRACING_SAMPLE_RATIO", target: &c.Tracing.SampleRatio},
+		{key: "CLAMD_ADDRESS", target: &c.Scan.ClamdAddress},
+		{key: "SCAN_FAIL_MODE", target: &c.Scan.FailMode},
+		{key: "SCAN_TIMEOUT", target: &c.Scan.Timeout},
+	}
+}
+
+// defaults returns the configuration used when nothing is set
+func defaults() *Config {
+	return &Config{
+		Mode:     "debug",
+		Port:     "8080",
+		LogLevel: "info",
+		HTTP: HTTPConfig{
+			ReadTimeout:     time.Minute,
+			WriteTimeout:    5 * time.Minute,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 25 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Migrate: "auto",
+		},
+		Blob: BlobConfig{
+			Backend:     "fs",
+			Dir:         "data/blobs",
+			S3PathStyle: true,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+			TOTPIssuer:      "CV Admin",
+			AdminUsername:   "admin",
+		},
+		CV: CVConfig{
+			DefaultLanguage: "en",
+			OwnerName:       "Nenad Mihajlovic",
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "http://localhost:4318",
+			SampleRatio:  1,
+		},
+		Scan: ScanConfig{
+			FailMode: "closed",
+			Timeout:  30 * time.Second,
+		},
+		ReadinessTimeout: 2 * time.Second,
+		sources:          make(map[string]string),
+	}
+}
+
+// Load reads the configuration from CONFIG_FILE, the environment and secret
+// files and validates it. The error lists every invalid setting.
+func Load() (*Config, error) {
+	cfg := defaults()
+	settings := cfg.settings()
+
+	known := make(map[string]bool, 2*len(settings))
+	for _, s := range settings {
+		known[s.key] = true
+		known[s.key+"_FILE"] = true
+	}
+
+	// Raw values by name and the layer they came from; later layers win
+	raw := make(map[string]string)
+	layer := make(map[string]string)
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			if !known[key] {
+				return nil, fmt.Errorf("unknown setting %s in %s", key, path)
+			}
+			raw[key], layer[key] = value, "file"
+		}
+	}
+
+	for key := range known {
+		if value := os.Getenv(key); value != "" {
+			raw[key], layer[key] = value, "env"
+		}
+	}
+
+	var problems []string
+	for _, s := range settings {
+		value, hasValue := raw[s.key]
+		path, hasPath := raw[s.key+"_FILE"]
+		source := layer[s.key]
+
+		switch {
+		case hasValue && hasPath:
+			problems = append(problems, fmt.Sprintf("set either %s or %s_FILE, not both", s.key, s.key))
+			continue
+		case hasPath:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("failed to read %s_FILE: %v", s.key, err))
+				continue
+			}
+			value, hasValue = strings.TrimRight(string(content), "\r\n"), true
+			source = s.key + "_FILE"
+		}
+		if !hasValue {
+			cfg.sources[s.key] = "default"
+			continue
+		}
+
+		if err := s.set(value); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid %s: %v", s.key, err))
+			continue
+		}
+		cfg.sources[s.key] = source
+	}
+
+	// Development conveniences that must be set explicitly in release mode
+	if !cfg.Release() {
+		if cfg.Database.URL == "" {
+			cfg.Database.URL = "sqlite://data/cv.db"
+			cfg.sources["DATABASE_URL"] = "default (development)"
+		}
+		if cfg.Auth.AdminPassword == "" {
+			cfg.Auth.AdminPassword = DefaultAdminPassword
+			cfg.sources["ADMIN_PASSWORD"] = "default (development)"
+		}
+		if cfg.sources["LOG_LEVEL"] == "default" {
+			cfg.LogLevel = "debug"
+			cfg.sources["LOG_LEVEL"] = "default (development)"
+		}
+	}
+
+	if len(problems) == 0 {
+		problems = cfg.validate()
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return cfg, nil
+}
+
+// readFile reads a flat YAML or TOML file of settings, chosen by extension
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &document)
+	case ".toml":
+		err = toml.Unmarshal(content, &document)
+	default:
+		return nil, fmt.Errorf("unsupported config file %s (use .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	values := make(map[string]string, len(document))
+	for key, value := range document {
+		key = strings.ToUpper(key)
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case bool, int, int64, float64:
+			values[key] = fmt.Sprint(v)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("setting %s in %s must be a string, number, boolean or list", key, path)
+		}
+	}
+	return values, nil
+}
+
+// set parses value into the setting's field
+func (s setting) set(value string) error {
+	switch target := s.target.(type) {
+	case *string:
+		*target = value
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("expected true or false")
+		}
+		*target = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("expected a duration such as 15m or 720h")
+		}
+		*target = d
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("expected a number")
+		}
+		*target = f
+	case *[]string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*target = items
+	}
+	return nil
+}
+
+// validate normalizes the settings and returns a description of every invalid one
+func (c *Config) validate() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch c.Mode {
+	case "debug", "release", "test":
+	default:
+		add("GIN_MODE must be debug, release or test")
+	}
+	c.LogLevel = strings.ToLower(c.LogLevel)
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		add("LOG_LEVEL must be debug, info, warn or error")
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		add("PORT must be a port number")
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				add("TRUSTED_PROXIES entry %q is not an IP address or CIDR", proxy)
+			}
+		}
+	}
+
+	if c.HTTP.ReadTimeout < 0 || c.HTTP.WriteTimeout < 0 || c.HTTP.IdleTimeout < 0 {
+		add("HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT and HTTP_IDLE_TIMEOUT must not be negative")
+	}
+	if c.HTTP.ShutdownDelay < 0 {
+		add("SHUTDOWN_DELAY must not be negative")
+	}
+	if c.HTTP.ShutdownTimeout <= 0 {
+		add("SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.ReadinessTimeout <= 0 {
+		add("READINESS_TIMEOUT must be positive")
+	}
+
+	// Database
+	if c.Database.URL == "" {
+		add("DATABASE_URL is required in release mode")
+	} else if strings.Contains(c.Database.URL, "://") {
+		if parsed, err := url.Parse(c.Database.URL); err != nil {
+			add("DATABASE_URL is not a valid URL")
+		} else {
+			switch parsed.Scheme {
+			case "postgres", "postgresql", "sqlite":
+			default:
+				add("DATABASE_URL scheme must be postgres, postgresql or sqlite")
+			}
+		}
+	}
+	if c.Database.Migrate != "auto" && c.Database.Migrate != "check" {
+		add("DB_MIGRATE must be auto or check")
+	}
+
+	// Blob storage
+	c.Blob.Backend = strings.ToLower(c.Blob.Backend)
+	switch c.Blob.Backend {
+	case "fs", "filesystem":
+		if c.Blob.Dir == "" {
+			add("BLOB_DIR is required for the fs blob backend")
+		}
+	case "s3":
+		if c.Blob.S3Endpoint == "" || c.Blob.S3Bucket == "" {
+			add("S3_ENDPOINT and S3_BUCKET are required for the s3 blob backend")
+		}
+		if c.Blob.S3AccessKeyID == "" || c.Blob.S3SecretAccessKey == "" {
+			add("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for the s3 blob backend")
+		}
+	default:
+		add("BLOB_BACKEND must be fs or s3")
+	}
+
+	// Authentication
+	if c.Auth.JWTSecret == "" {
+		add("JWT_SECRET is required")
+	} else if len(c.Auth.JWTSecret) < minJWTSecretLength {
+		add("JWT_SECRET must be at least %d characters long", minJWTSecretLength)
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+		add("ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL must be positive")
+	} else if c.Auth.AccessTokenTTL >= c.Auth.RefreshTokenTTL {
+		add("ACCESS_TOKEN_TTL must be shorter than REFRESH_TOKEN_TTL")
+	}
+	if c.Auth.AdminUsername == "" {
+		add("ADMIN_USERNAME must not be empty")
+	}
+	if c.Auth.AdminPassword != "" && len(c.Auth.AdminPassword) < 8 {
+		add("ADMIN_PASSWORD must be at least 8 characters long")
+	}
+	if c.Release() && c.Auth.AdminPassword == DefaultAdminPassword {
+		add("ADMIN_PASSWORD must not be the default password in release mode")
+	}
+
+	// Tracing
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if parsed, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			add("OTLP_ENDPOINT must be an http or https URL")
+		}
+	default:
+		add("TRACING_EXPORTER must be none, otlp or stdout")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	// Malware scanning
+	if c.Scan.ClamdAddress != "" {
+		if parsed, err := url.Parse(c.Scan.ClamdAddress); err != nil ||
+			!(parsed.Scheme == "tcp" && parsed.Host != "" && parsed.Port() != "") && !(parsed.Scheme == "unix" && parsed.Path != "") {
+			add("CLAMD_ADDRESS must be tcp://host:port or unix:///path/to/socket")
+		}
+	}
+	if c.Scan.FailMode != "open" && c.Scan.FailMode != "closed" {
+		add("SCAN_FAIL_MODE must be open or closed")
+	}
+	if c.Scan.Timeout <= 0 {
+		add("SCAN_TIMEOUT must be positive")
+	}
+
+	// CV
+	c.CV.DefaultLanguage = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(c.CV.DefaultLanguage), "_", "-"))
+	if !languageTagPattern.MatchString(c.CV.DefaultLanguage) {
+		add("CV_DEFAULT_LANGUAGE must be a language tag such as en or de-at")
+	}
+
+	return problems
+}
+
+// Release reports whether the server runs in release mode
+func (c *Config) Release() bool {
+	return c.Mode == "release"
+}
+
+// Setting is one entry of the redacted configuration dump
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"` // default, file, env or the _FILE variable it was read from
+}
+
+// Redacted lists every setting in effect, sorted by name, with the values of
+// secrets masked
+func (c *Config) Redacted() []Setting {
+	var result []Setting
+	for _, s := range c.settings() {
+		value := s.format()
+		if s.redact != nil && value != "" {
+			value = s.redact(value)
+		}
+		result = append(result, Setting{Key: s.key, Value: value, Source: c.sources[s.key]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// format returns the value of the setting's field as text
+func (s setting) format() string {
+	switch target := s.target.(type) {
+	case *string:
+		return *target
+	case *bool:
+		return strconv.FormatBool(*target)
+	case *time.Duration:
+		return target.String()
+	case *float64:
+		return strconv.FormatFloat(*target, 'g', -1, 64)
+	case *[]string:
+		return strings.Join(*target, ",")
+	}
+	return ""
+}
+
+func maskSecret(string) string {
+	return "********"
+}
+
+// redactURL masks the password of a database URL, or the whole value if it
+// is not a URL
+func redactURL(value string) string {
+	if parsed, err := url.Parse(value); err == nil && strings.Contains(value, "://") {
+		return parsed.Redacted()
+	}
+	return maskSecret(value)
+}
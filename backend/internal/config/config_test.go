@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// setEnv clears every setting in the environment and sets the given ones
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range defaults().settings() {
+		t.Setenv(s.key, "")
+		t.Setenv(s.key+"_FILE", "")
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+// writeFile writes content to a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// source returns where the setting key came from
+func source(cfg *Config, key string) string {
+	for _, s := range cfg.Redacted() {
+		if s.Key == key {
+			return s.Source
+		}
+	}
+	return ""
+}
+
+func TestLoadValidatesJWTSecret(t *testing.T) {
+	for _, tt := range []struct {
+		name, secret, problem string
+	}{
+		{"missing", "", "JWT_SECRET is required"},
+		{"short", strings.Repeat("x", minJWTSecretLength-1), "JWT_SECRET must be at least 32 characters long"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, map[string]string{"JWT_SECRET": tt.secret})
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("Load() error = %v, want %q", err, tt.problem)
+			}
+		})
+	}
+
+	setEnv(t, map[string]string{"JWT_SECRET": testSecret})
+	if _, err := Load(); err != nil {
+		t.Errorf("Load() with a 32 character secret: %v", err)
+	}
+}
+
+func TestLoadReadsSecretFiles(t *testing.T) {
+	setEnv(t, map[string]string{
+		"JWT_SECRET_FILE":     writeFile(t, "jwt", testSecret+"\n"),
+		"ADMIN_PASSWORD_FILE": writeFile(t, "admin", "from a secret file\r\n"),
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Auth.JWTSecret != testSecret || cfg.Auth.AdminPassword != "from a secret file" {
+		t.Errorf("secrets = %q, %q; want the file contents without the line break", cfg.Auth.JWTSecret, cfg.Auth.AdminPassword)
+	}
+	if got := source(cfg, "JWT_SECRET"); got != "JWT_SECRET_FILE" {
+		t.Errorf("JWT_SECRET source = %q, want JWT_SECRET_FILE", got)
+	}
+	for _, s := range cfg.Redacted() {
+		if s.Key == "JWT_SECRET" && s.Value == testSecret {
+			t.Error("the configuration dump shows the JWT secret")
+		}
+	}
+}
+
+func TestLoadRejectsInvalidSecretFiles(t *testing.T) {
+	setEnv(t, map[string]string{
+		"JWT_SECRET":      testSecret,
+		"JWT_SECRET_FILE": writeFile(t, "jwt", testSecret),
+	})
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "set either JWT_SECRET or JWT_SECRET_FILE") {
+		t.Errorf("Load() with both: error = %v", err)
+	}
+
+	setEnv(t, map[string]string{"JWT_SECRET_FILE": filepath.Join(t.TempDir(), "missing")})
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "failed to read JWT_SECRET_FILE") {
+		t.Errorf("Load() with a missing file: error = %v", err)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	setEnv(t, map[string]string{
+		"CONFIG_FILE": writeFile(t, "config.yaml", "jwt_secret: "+testSecret+"\nport: 9000\nlog_level: warn\n"),
+		"LOG_LEVEL":   "error",
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != "9000" || source(cfg, "PORT") != "file" {
+		t.Errorf("PORT = %q from %q, want 9000 from the file", cfg.Port, source(cfg, "PORT"))
+	}
+	// The environment takes precedence over the file
+	if cfg.LogLevel != "error" || source(cfg, "LOG_LEVEL") != "env" {
+		t.Errorf("LOG_LEVEL = %q from %q, want error from env", cfg.LogLevel, source(cfg, "LOG_LEVEL"))
+	}
+
+	setEnv(t, map[string]string{"CONFIG_FILE": writeFile(t, "config.toml", "jwt_secret = \""+testSecret+"\"\nunknown = 1\n")})
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "unknown setting") {
+		t.Errorf("Load() with an unknown setting: error = %v", err)
+	}
+}
+
+func TestLoadReleaseMode(t *testing.T) {
+	setEnv(t, map[string]string{"GIN_MODE": "release", "JWT_SECRET": testSecret})
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "DATABASE_URL is required in release mode") {
+		t.Errorf("Load() without a database: error = %v", err)
+	}
+
+	setEnv(t, map[string]string{
+		"GIN_MODE":       "release",
+		"JWT_SECRET":     testSecret,
+		"DATABASE_URL":   "postgres://cv:secret@db:5432/cv",
+		"ADMIN_PASSWORD": DefaultAdminPassword,
+	})
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "must not be the default password") {
+		t.Errorf("Load() with the default admin password: error = %v", err)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	setEnv(t, map[string]string{
+		"JWT_SECRET":     "short",
+		"PORT":           "99999",
+		"SCAN_FAIL_MODE": "sometimes",
+	})
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() succeeded")
+	}
+	for _, problem := range []string{"JWT_SECRET", "PORT", "SCAN_FAIL_MODE"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("error %q does not mention %s", err, problem)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"cv-backend/internal/metrics"
+	"cv-backend/internal/models"
+	"cv-backend/internal/storage"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsHandler struct {
+	analyticsStorage *storage.AnalyticsStorage
+}
+
+func NewAnalyticsHandler(stores *storage.Stores) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsStorage: stores.Analytics,
+	}
+}
+
+// defaultRanges are the periods shown when no ?from= is given
+var defaultRanges = map[string]func(time.Time) time.Time{
+	"day":   func(to time.Time) time.Time { return to.AddDate(0, 0, -30) },
+	"week":  func(to time.Time) time.Time { return to.AddDate(0, 0, -7*12) },
+	"month": func(to time.Time) time.Time { return to.AddDate(-1, 0, 0) },
+}
+
+// parseRange reads ?from= and ?to= (YYYY-MM-DD, both inclusive) and returns
+// the half-open interval [from, to). It writes a 400 response on bad input.
+func parseRange(c *gin.Context, granularity string) (time.Time, time.Time, bool) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today.AddDate(0, 0, 1)
+	if value := c.Query("to"); value != "" {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' date, expected YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+
+	from := defaultRanges[granularity](to)
+	if value := c.Query("from"); value != "" {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' date, expected YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = day
+	}
+
+	if !from.Before(to) || to.Sub(from) > 10*366*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// Timeseries returns views, downloads and unique visitors per day, week or month (protected endpoint)
+func (h *AnalyticsHandler) Timeseries(c *gin.Context) {
+	granularity := c.DefaultQuery("granularity", "day")
+	if _, ok := defaultRanges[granularity]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be day, week or month"})
+		return
+	}
+
+	from, to, ok := parseRange(c, granularity)
+	if !ok {
+		return
+	}
+	includeBots := c.Query("includeBots") == "true"
+
+	buckets, err := h.analyticsStorage.Timeseries(c.Request.Context(), from, to, granularity, includeBots)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	uniqueVisitors, err := h.analyticsStorage.UniqueVisitors(c.Request.Context(), from, to, includeBots)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	views, downloads := 0, 0
+	for _, bucket := range buckets {
+		views += bucket.Views
+		downloads += bucket.Downloads
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"granularity": granularity,
+		"from":        from.Format("2006-01-02"),
+		"to":          to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"buckets":     buckets,
+		"totals": gin.H{
+			"views":          views,
+			"downloads":      downloads,
+			"uniqueVisitors": uniqueVisitors,
+		},
+	})
+}
+
+// TopReferrers returns the hosts that sent the most visitors (protected endpoint)
+func (h *AnalyticsHandler) TopReferrers(c *gin.Context) {
+	from, to, ok := parseRange(c, "day")
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	referrers, err := h.analyticsStorage.TopReferrers(c.Request.Context(), from, to, limit, c.Query("includeBots") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"referrers": referrers})
+}
+
+// VersionBreakdown returns views and downloads per CV version (protected endpoint)
+func (h *AnalyticsHandler) VersionBreakdown(c *gin.Context) {
+	from, to, ok := parseRange(c, "month")
+	if !ok {
+		return
+	}
+
+	versions, err := h.analyticsStorage.VersionBreakdown(c.Request.Context(), from, to, c.Query("includeBots") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// accessRecorder turns requests into anonymized access events
+type accessRecorder struct {
+	storage *storage.AnalyticsStorage
+	salt    []byte
+}
+
+func newAccessRecorder(configuredSalt string, analytics *storage.AnalyticsStorage) *accessRecorder {
+	// Visitor IPs are only stored as a keyed hash. Without a configured salt
+	// a random one is used, so unique visitors are not linked across restarts.
+	salt := []byte(configuredSalt)
+	if len(salt) == 0 {
+		salt = make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			slog.Warn("failed to generate analytics salt", "error", err)
+		}
+	}
+
+	return &accessRecorder{
+		storage: analytics,
+		salt:    salt,
+	}
+}
+
+// countsAsAccess reports whether a served request fetched the file, as
+// opposed to a HEAD request, a 304 revalidation or a Range request continuing
+// an earlier download. It counts the same requests as the share link
+// download cap (see fetchesFile).
+func countsAsAccess(c *gin.Context, cvFile *models.CVFile) bool {
+	if c.Request.Method != http.MethodGet {
+		return false
+	}
+	switch c.Writer.Status() {
+	case http.StatusOK:
+		return true
+	case http.StatusPartialContent:
+		return !ifRangeMatches(c, cvFile)
+	}
+	return false
+}
+
+// record stores an access event for the served CV version. shareLink is the
+// link the CV was reached through, or nil for public access.
+func (r *accessRecorder) record(c *gin.Context, cvFile *models.CVFile, kind string, shareLink *models.ShareLink) {
+	if !countsAsAccess(c, cvFile) {
+		return
+	}
+	metrics.CVAccesses.Inc(kind, strconv.FormatUint(uint64(cvFile.ID), 10))
+
+	event := &models.CVAccessEvent{
+		CVFileID:       cvFile.ID,
+		Kind:           kind,
+		OccurredAt:     time.Now(),
+		Referrer:       referrerHost(c.GetHeader("Referer")),
+		UserAgentClass: classifyUserAgent(c.GetHeader("User-Agent")),
+		IPHash:         r.hashIP(c.ClientIP()),
+	}
+	if shareLink != nil {
+		event.ShareLinkID = &shareLink.ID
+	}
+	// The file has been served, so record the access even if the client
+	// has disconnected in the meantime
+	if err := r.storage.RecordAccess(context.WithoutCancel(c.Request.Context()), event); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to record CV access", "error", err)
+	}
+}
+
+func (r *accessRecorder) hashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// referrerHost reduces a Referer header to its host name
+func referrerHost(referer string) string {
+	if referer == "" {
+		return ""
+	}
+	u, err := url.Parse(referer)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// classifyUserAgent sorts clients into coarse classes without storing the full header
+func classifyUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return "other"
+	case containsAny(ua, "bot", "crawler", "spider", "slurp", "curl", "wget", "python-requests", "go-http-client", "headless", "preview"):
+		return storage.UserAgentBot
+	case containsAny(ua, "mobile", "android", "iphone", "ipad"):
+		return "mobile"
+	case containsAny(ua, "windows", "macintosh", "x11", "linux", "cros"):
+		return "desktop"
+	default:
+		return "other"
+	}
+}
+
+func containsAny(s string, substrings ...string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"cv-backend/internal/models"
+	"cv-backend/internal/storage"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyResponse is an API key as shown to its user
+type APIKeyResponse struct {
+	models.APIKey
+	Scopes []string `json:"scopes"`
+	Active bool     `json:"active"`
+}
+
+func newAPIKeyResponse(key models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		APIKey: key,
+		Scopes: key.ScopeList(),
+		Active: key.Active(time.Now()),
+	}
+}
+
+// ListAPIKeys returns the API keys of the current user (protected endpoint)
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeys.ListAPIKeys(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, newAPIKeyResponse(key))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"apiKeys": response,
+		"count":   len(response),
+	})
+}
+
+// CreateAPIKey creates an API key for the current user. The key is only
+// returned in this response (protected endpoint).
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be 1-100 characters long"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+
+	// A key cannot do more than its user
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool)
+	for _, scope := range req.Scopes {
+		role := models.ScopeRole(scope)
+		if role == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+		if !user.HasRole(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot grant the " + scope + " scope"})
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+		return
+	}
+
+	apiKey, key, err := h.apiKeys.CreateAPIKey(c.Request.Context(), user.ID, name, scopes, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	h.recordUserAudit(c, storage.AuditAPIKeyCreated, user.Username, apiKey.Name+" ("+apiKey.Scopes+")")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"apiKey": newAPIKeyResponse(*apiKey),
+		"key":    key,
+	})
+}
+
+// RevokeAPIKey disables an API key of the current user; owners can revoke
+// any key (protected endpoint)
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	apiKey, err := h.apiKeys.GetAPIKey(c.Request.Context(), uint(id))
+	if err == nil && apiKey.UserID != user.ID && !user.HasRole(models.RoleOwner) {
+		err = storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	apiKey, err = h.apiKeys.RevokeAPIKey(c.Request.Context(), apiKey.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	keyOwner := user.Username
+	if apiKey.UserID != user.ID {
+		if owner, err := h.userStorage.GetUserByID(c.Request.Context(), apiKey.UserID); err == nil {
+			keyOwner = owner.Username
+		}
+	}
+	h.recordUserAudit(c, storage.AuditAPIKeyRevoked, keyOwner, apiKey.Name)
+
+	c.JSON(http.StatusOK, newAPIKeyResponse(*apiKey))
+}
@@ -1,24 +1,61 @@
 package handlers
 
 import (
+	"context"
 	"cv-backend/internal/auth"
+	"cv-backend/internal/config"
+	"cv-backend/internal/metrics"
 	"cv-backend/internal/models"
 	"cv-backend/internal/storage"
+	"errors"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-type AuthHandler struct{
-	userStorage *storage.UserStorage
+type AuthHandler struct {
+	userStorage   storage.UserStore
+	refreshTokens *storage.RefreshTokenStorage
+	twoFactor     *storage.TwoFactorStorage
+	throttle      *storage.LoginThrottleStorage
+	audit         *storage.AuditStorage
+	apiKeys       *storage.APIKeyStorage
+	totpIssuer    string // account issuer shown in authenticator apps
 }
 
-func NewAuthHandler() *AuthHandler {
+func NewAuthHandler(cfg *config.Config, stores *storage.Stores) *AuthHandler {
 	return &AuthHandler{
-		userStorage: storage.NewUserStorage(),
+		userStorage:   stores.Users,
+		refreshTokens: stores.RefreshTokens,
+		twoFactor:     stores.TwoFactor,
+		throttle:      stores.LoginThrottle,
+		audit:         stores.Audit,
+		apiKeys:       stores.APIKeys,
+		totpIssuer:    cfg.Auth.TOTPIssuer,
 	}
 }
 
+// issueTokens creates an access token for the user's current token version.
+// A new session is started unless refreshToken continues an existing one.
+func (h *AuthHandler) issueTokens(c *gin.Context, user *models.User, refreshToken string) (string, string, error) {
+	token, err := auth.GenerateToken(user.Username, user.TokenVersion)
+	if err != nil {
+		return "", "", err
+	}
+
+	if refreshToken == "" {
+		refreshToken, err = h.refreshTokens.IssueRefreshToken(c.Request.Context(), user.ID, c.Request.UserAgent())
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return token, refreshToken, nil
+}
+
 // Login handles admin login
 func (h *AuthHandler) Login(c *gin.Context) {
 	var loginReq models.LoginRequest
@@ -27,27 +64,205 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Refuse attempts while the account or the client IP is backing off
+	if !h.allowLoginAttempt(c, loginReq.Username) {
+		return
+	}
+
 	// Validate credentials using file-based storage
-	user, err := h.userStorage.ValidatePassword(loginReq.Username, loginReq.Password)
+	user, err := h.userStorage.ValidatePassword(c.Request.Context(), loginReq.Username, loginReq.Password)
 	if err != nil {
+		h.recordLoginFailure(c, loginReq.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(loginReq.Username)
+	// With 2FA enabled the password only unlocks the second step
+	if user.TOTPEnabled {
+		challengeToken, err := auth.GenerateChallengeToken(user.Username, user.TokenVersion)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+			Message:           "Two-factor code required",
+		})
+		return
+	}
+
+	h.recordLoginSuccess(c, user.Username)
+
+	// Generate JWT access token and refresh token
+	token, refreshToken, err := h.issueTokens(c, user, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL().Seconds()),
+		Message:      "Login successful",
+		FirstLogin:   user.FirstLogin,
+	})
+}
+
+// allowLoginAttempt writes a 429 response with Retry-After if logins for the
+// username or from the client IP are currently refused
+func (h *AuthHandler) allowLoginAttempt(c *gin.Context, username string) bool {
+	wait, err := h.throttle.RetryAfter(c.Request.Context(), username, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+	if wait > 0 {
+		metrics.Logins.Inc("throttled")
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many failed login attempts, please try again later",
+			"retry_after": retryAfter,
+		})
+		return false
+	}
+	return true
+}
+
+func (h *AuthHandler) recordLoginFailure(c *gin.Context, username string) {
+	metrics.Logins.Inc("failure")
+	if err := h.throttle.RecordFailure(c.Request.Context(), username, c.ClientIP()); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to record login failure", "error", err)
+	}
+}
+
+func (h *AuthHandler) recordLoginSuccess(c *gin.Context, username string) {
+	metrics.Logins.Inc("success")
+	if err := h.throttle.RecordSuccess(c.Request.Context(), username); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to record login success", "error", err)
+	}
+}
+
+// verifyPassword checks the password of a signed-in user again, for changes
+// that require it, and writes a 401 or 429 response if it cannot be
+// confirmed. Failures count towards the same lockouts as logins, so a stolen
+// access token cannot be used to guess the password.
+func (h *AuthHandler) verifyPassword(c *gin.Context, username, password string) bool {
+	if !h.checkPassword(c, username, password) {
+		return false
+	}
+	h.resetFailures(c, username)
+	return true
+}
+
+// checkPassword is verifyPassword without resetting the failure count, for
+// changes that also require a two-factor code
+func (h *AuthHandler) checkPassword(c *gin.Context, username, password string) bool {
+	if !h.allowLoginAttempt(c, username) {
+		return false
+	}
+
+	if _, err := h.userStorage.ValidatePassword(c.Request.Context(), username, password); err != nil {
+		if err := h.throttle.RecordFailure(c.Request.Context(), username, c.ClientIP()); err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to record password failure", "error", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return false
+	}
+	return true
+}
+
+// resetFailures clears the failure count of a signed-in user once every
+// check of a change has passed
+func (h *AuthHandler) resetFailures(c *gin.Context, username string) {
+	if err := h.throttle.RecordSuccess(c.Request.Context(), username); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to record password success", "error", err)
+	}
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var refreshReq models.RefreshRequest
+	if err := c.ShouldBindJSON(&refreshReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	user, refreshToken, err := h.refreshTokens.RotateRefreshToken(c.Request.Context(), refreshReq.RefreshToken, c.Request.UserAgent())
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	token, refreshToken, err := h.issueTokens(c, user, refreshToken)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, models.LoginResponse{
-		Token:      token,
-		Message:    "Login successful",
-		FirstLogin: user.FirstLogin,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL().Seconds()),
+		Message:      "Token refreshed",
+		FirstLogin:   user.FirstLogin,
+	})
+}
+
+// Logout ends the session of the given refresh token. The access token stays
+// valid until it expires, which is why access tokens are short-lived.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var logoutReq models.RefreshRequest
+	if err := c.ShouldBindJSON(&logoutReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Logging out with an unknown or already revoked token is not an error
+	if err := h.refreshTokens.RevokeRefreshToken(c.Request.Context(), logoutReq.RefreshToken); err != nil && !errors.Is(err, storage.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out successfully",
 	})
 }
 
+// LogoutAll revokes every refresh token and access token of the current user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.revokeAllTokens(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "All sessions logged out",
+	})
+}
+
+// revokeAllTokens ends every session of a user
+func (h *AuthHandler) revokeAllTokens(ctx context.Context, user *models.User) error {
+	if err := h.userStorage.IncrementTokenVersion(ctx, user.Username); err != nil {
+		return err
+	}
+	return h.refreshTokens.RevokeAllForUser(ctx, user.ID)
+}
+
 // VerifyToken checks if the provided token is valid
 func (h *AuthHandler) VerifyToken(c *gin.Context) {
 	username, exists := c.Get("username")
@@ -57,7 +272,7 @@ func (h *AuthHandler) VerifyToken(c *gin.Context) {
 	}
 
 	// Get user to check if first login
-	user, err := h.userStorage.GetUser(username.(string))
+	user, err := h.userStorage.GetUser(c.Request.Context(), username.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
 		return
@@ -66,6 +281,7 @@ func (h *AuthHandler) VerifyToken(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"valid":       true,
 		"username":    username,
+		"role":        user.Role,
 		"message":     "Token is valid",
 		"first_login": user.FirstLogin,
 	})
@@ -86,9 +302,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	// Verify current password
-	_, err := h.userStorage.ValidatePassword(username.(string), changeReq.CurrentPassword)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+	if !h.verifyPassword(c, username.(string), changeReq.CurrentPassword) {
 		return
 	}
 
@@ -98,14 +312,34 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	// Change password
-	if err := h.userStorage.ChangePassword(username.(string), changeReq.NewPassword); err != nil {
+	// Change password; this also invalidates all access tokens issued so far
+	if err := h.userStorage.ChangePassword(c.Request.Context(), username.(string), changeReq.NewPassword); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
 		return
 	}
 
+	// End all other sessions and start a new one for this client
+	user, err := h.userStorage.GetUser(c.Request.Context(), username.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+		return
+	}
+	if err := h.refreshTokens.RevokeAllForUser(c.Request.Context(), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	token, refreshToken, err := h.issueTokens(c, user, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
 	c.JSON(http.StatusOK, models.ChangePasswordResponse{
-		Success: true,
-		Message: "Password changed successfully",
+		Success:      true,
+		Message:      "Password changed successfully",
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL().Seconds()),
 	})
-}
\ No newline at end of file
+}
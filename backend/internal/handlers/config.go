@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"cv-backend/internal/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigHandler struct {
+	cfg *config.Config
+}
+
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{cfg: cfg}
+}
+
+// GetConfig returns the settings in effect with secrets redacted (protected endpoint)
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"settings": h.cfg.Redacted()})
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"cv-backend/internal/models"
+	"cv-backend/internal/storage"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContentHandler struct {
+	contentStorage *storage.ContentStorage
+}
+
+func NewContentHandler(stores *storage.Stores) *ContentHandler {
+	return &ContentHandler{
+		contentStorage: stores.Content,
+	}
+}
+
+// ReorderRequest lists the IDs of a section's entries in their new order
+type ReorderRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// GetPublicContent returns the visible entries of every section (public endpoint)
+func (h *ContentHandler) GetPublicContent(c *gin.Context) {
+	content, err := h.contentStorage.PublicContent(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get CV content"})
+		return
+	}
+
+	c.JSON(http.StatusOK, content)
+}
+
+// ListEntries returns all entries of a section, including hidden ones (protected endpoint)
+func (h *ContentHandler) ListEntries(c *gin.Context) {
+	entries, err := h.contentStorage.ListEntries(c.Request.Context(), c.Param("section"), true)
+	if err != nil {
+		h.writeError(c, err, "Failed to list entries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// CreateEntry adds an entry to a section (protected endpoint)
+func (h *ContentHandler) CreateEntry(c *gin.Context) {
+	entry, err := h.contentStorage.NewEntry(c.Param("section"))
+	if err != nil {
+		h.writeError(c, err, "Failed to create entry")
+		return
+	}
+
+	if err := c.ShouldBindJSON(entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	entry.Entry().ID = 0
+
+	if err := h.contentStorage.CreateEntry(c.Request.Context(), entry); err != nil {
+		h.writeError(c, err, "Failed to create entry")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// UpdateEntry updates an entry; fields missing from the body keep their values (protected endpoint)
+func (h *ContentHandler) UpdateEntry(c *gin.Context) {
+	id, ok := parseEntryID(c)
+	if !ok {
+		return
+	}
+
+	entry, err := h.contentStorage.GetEntry(c.Request.Context(), c.Param("section"), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to update entry")
+		return
+	}
+
+	if err := c.ShouldBindJSON(entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	entry.Entry().ID = id
+
+	if err := h.contentStorage.SaveEntry(c.Request.Context(), entry); err != nil {
+		h.writeError(c, err, "Failed to update entry")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteEntry removes an entry from a section (protected endpoint)
+func (h *ContentHandler) DeleteEntry(c *gin.Context) {
+	id, ok := parseEntryID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.contentStorage.DeleteEntry(c.Request.Context(), c.Param("section"), id); err != nil {
+		h.writeError(c, err, "Failed to delete entry")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Entry deleted successfully",
+	})
+}
+
+// ReorderEntries sets the display order of a section (protected endpoint)
+func (h *ContentHandler) ReorderEntries(c *gin.Context) {
+	var req ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if err := h.contentStorage.ReorderEntries(c.Request.Context(), c.Param("section"), req.IDs); err != nil {
+		h.writeError(c, err, "Failed to reorder entries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Entries reordered successfully",
+	})
+}
+
+// ImportContent replaces whole sections with the entries in the request body,
+// e.g. {"experiences": [...], "skills": [...]}. The format matches the static
+// JSON files under frontend/src/data. Sections missing from the body are left
+// unchanged (protected endpoint).
+func (h *ContentHandler) ImportContent(c *gin.Context) {
+	var req map[string][]json.RawMessage
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	imported := make(map[string][]models.ContentItem, len(req))
+	for section, rawEntries := range req {
+		entries := make([]models.ContentItem, 0, len(rawEntries))
+		for _, raw := range rawEntries {
+			entry, err := h.contentStorage.NewEntry(section)
+			if err != nil {
+				h.writeError(c, err, "Failed to import content")
+				return
+			}
+			if err := json.Unmarshal(raw, entry); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry in section " + section})
+				return
+			}
+			entries = append(entries, entry)
+		}
+		imported[section] = entries
+	}
+
+	counts := make(map[string]int, len(imported))
+	for section, entries := range imported {
+		if err := h.contentStorage.ReplaceSection(c.Request.Context(), section, entries); err != nil {
+			h.writeError(c, err, "Failed to import content")
+			return
+		}
+		counts[section] = len(entries)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Content imported successfully",
+		"imported": counts,
+	})
+}
+
+// writeError maps content storage errors to HTTP responses
+func (h *ContentHandler) writeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrUnknownContentSection):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown content section"})
+	case errors.Is(err, storage.ErrContentEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// parseEntryID reads the :id path parameter and writes a 400 response if it is invalid
+func parseEntryID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -1,122 +1,383 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"cv-backend/internal/config"
+	"cv-backend/internal/metrics"
+	"cv-backend/internal/models"
+	"cv-backend/internal/pdfcheck"
+	"cv-backend/internal/scanner"
+	"cv-backend/internal/storage"
+	"cv-backend/internal/tracing"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
-	"cv-backend/internal/storage"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CVHandler struct {
-	cvStorage *storage.CVStorage
+	cvStorage       storage.CVStore
+	contentStorage  *storage.ContentStorage
+	analytics       *storage.AnalyticsStorage
+	shareLinks      *storage.ShareLinkStorage
+	throttle        *storage.LoginThrottleStorage
+	settings        *storage.SettingsStorage
+	audit           *storage.AuditStorage
+	scanner         scanner.Scanner // nil if scanning is disabled
+	scanFailOpen    bool            // accept uploads unscanned when the scanner fails
+	recorder        *accessRecorder
+	defaultLanguage string // served when neither ?lang= nor Accept-Language match a CV
+	ownerName       string // printed on generated CVs
+	ownerHeadline   string
 }
 
-func NewCVHandler() *CVHandler {
+func NewCVHandler(cfg *config.Config, stores *storage.Stores, uploadScanner scanner.Scanner) *CVHandler {
 	return &CVHandler{
-		cvStorage: storage.NewCVStorage(),
+		cvStorage:       stores.CVs,
+		contentStorage:  stores.Content,
+		analytics:       stores.Analytics,
+		shareLinks:      stores.ShareLinks,
+		throttle:        stores.LoginThrottle,
+		settings:        stores.Settings,
+		audit:           stores.Audit,
+		scanner:         uploadScanner,
+		scanFailOpen:    cfg.Scan.FailMode == "open",
+		recorder:        newAccessRecorder(cfg.AnalyticsSalt, stores.Analytics),
+		defaultLanguage: cfg.CV.DefaultLanguage,
+		ownerName:       cfg.CV.OwnerName,
+		ownerHeadline:   cfg.CV.OwnerHeadline,
 	}
 }
 
-// UploadCV handles CV file upload (protected endpoint)
+// maxCVSize is the largest CV file accepted for upload
+const maxCVSize = 10 << 20
+
+// maxUploadFormOverhead allows for multipart headers and the other form
+// fields on top of the file
+const maxUploadFormOverhead = 1 << 20
+
+// maxUploadMemory is how much of an upload form is kept in memory; larger
+// files are spooled to temporary files while the form is parsed
+const maxUploadMemory = 1 << 20
+
+// UploadCV handles CV file upload (protected endpoint). The file is checked
+// by content: it must parse as a PDF without encryption, JavaScript or
+// launch actions. The file name and client-supplied content type are ignored.
+// Files flagged by the malware scanner are stored quarantined. Uploading the
+// current file again creates no new version. The upload is streamed to a
+// temporary file, which the checks, the scanner and the storage read from,
+// and its SHA-256 is computed on the way.
 func (h *CVHandler) UploadCV(c *gin.Context) {
+	// Stop reading oversized bodies instead of buffering them
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCVSize+maxUploadFormOverhead)
+
 	// Get the uploaded file
-	file, header, err := c.Request.FormFile("cv")
+	err := c.Request.ParseMultipartForm(maxUploadMemory)
+	var file multipart.File
+	var header *multipart.FileHeader
+	if err == nil {
+		file, header, err = c.Request.FormFile("cv")
+	}
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File size exceeds 10MB limit", "code": "file_too_large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 		return
 	}
+	defer c.Request.MultipartForm.RemoveAll()
 	defer file.Close()
 
-	// Validate file type
-	if !strings.HasSuffix(strings.ToLower(header.Filename), ".pdf") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only PDF files are allowed"})
+	// Validate file size (10MB limit) by copying at most one byte more,
+	// hashing the content on the way
+	tmp, err := os.CreateTemp("", "cv-upload-*.pdf")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store uploaded file"})
 		return
 	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
-	// Validate file size (10MB limit)
-	if header.Size > 10*1024*1024 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File size exceeds 10MB limit"})
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(file, maxCVSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	if size > maxCVSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File size exceeds 10MB limit", "code": "file_too_large"})
 		return
 	}
 
-	// Get content type
-	contentType := header.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "application/pdf"
+	// Validate file content
+	if err := validateCVFile(tmp, size); err != nil {
+		if code := pdfcheck.Code(err); code != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CV file: " + err.Error(), "code": code})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
 	}
 
-	// Upload CV using file storage
-	cvFile, err := h.cvStorage.UploadCV(file, header.Filename, header.Size, contentType)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save CV: %v", err)})
+	// Language of this CV variant (defaults to the configured default language)
+	language := h.defaultLanguage
+	if lang := c.PostForm("lang"); lang != "" {
+		normalized, ok := normalizeLanguage(lang)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language tag"})
+			return
+		}
+		language = normalized
+	}
+
+	// Optional note describing what changed in this version
+	changeNote := strings.TrimSpace(c.PostForm("note"))
+
+	digest := hex.EncodeToString(hasher.Sum(nil))
+	cvFile, ok := h.unchangedCV(c, language, digest, changeNote)
+	if !ok {
 		return
 	}
+	unchanged := cvFile != nil
+	message := "CV unchanged from current version"
+
+	if !unchanged {
+		scan, ok := h.scanCV(c, io.NewSectionReader(tmp, 0, size))
+		if !ok {
+			return
+		}
+
+		// Upload CV using file storage
+		cvFile, err = h.cvStorage.UploadCV(c.Request.Context(), io.NewSectionReader(tmp, 0, size), header.Filename, size, digest, pdfcheck.ContentType, language, changeNote, scan)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save CV: %v", err)})
+			return
+		}
+		if cvFile.Quarantined() {
+			h.rejectQuarantined(c, cvFile)
+			return
+		}
+		message = "CV uploaded successfully"
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":     true,
-		"message":     "CV uploaded successfully",
-		"filename":    cvFile.FileName,
+		"success":      true,
+		"message":      message,
+		"unchanged":    unchanged,
+		"sha256":       cvFile.SHA256,
+		"filename":     cvFile.FileName,
 		"originalName": cvFile.OriginalName,
-		"size":        cvFile.FileSize,
-		"uploadedAt":  cvFile.CreatedAt,
+		"size":         cvFile.FileSize,
+		"uploadedAt":   cvFile.CreatedAt,
+		"versionId":    cvFile.ID,
+		"language":     cvFile.Language,
+		"changeNote":   cvFile.ChangeNote,
 	})
 }
 
-// DownloadCV serves the current CV file (public endpoint)
-func (h *CVHandler) DownloadCV(c *gin.Context) {
-	// Get current CV from file storage with data
-	cvFile, data, err := h.cvStorage.GetCurrentCVWithData()
+// validateCVFile checks the first size bytes of f with pdfcheck. The parser
+// needs random access to the whole file, so it is read into memory for the
+// check only, not while the file is scanned and stored.
+func validateCVFile(f *os.File, size int64) error {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, size))
+	if err != nil {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	return pdfcheck.Validate(data)
+}
+
+// unchangedCV returns the current CV of the language if its content has the
+// given SHA-256, or nil otherwise. It writes an error response and returns
+// false if the current CV cannot be looked up, or if it is unchanged but a
+// change note was given, which would describe a change that did not happen.
+func (h *CVHandler) unchangedCV(c *gin.Context, language, digest, changeNote string) (*models.CVFile, bool) {
+	current, err := h.cvStorage.GetCurrentCV(c.Request.Context(), language)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get current CV"})
-		return
+		return nil, false
+	}
+	if current == nil || current.SHA256 != digest {
+		return nil, true
+	}
+	if changeNote != "" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "CV unchanged from current version; the change note was not saved",
+			"code":      "cv_unchanged",
+			"versionId": current.ID,
+		})
+		return nil, false
+	}
+	return current, true
+}
+
+// scanCV checks the file read from r with the malware scanner. If the scanner fails, the
+// file counts as unscanned with SCAN_FAIL_MODE=open; with closed, an error
+// response is written and false returned.
+func (h *CVHandler) scanCV(c *gin.Context, r io.Reader) (models.ScanResult, bool) {
+	if h.scanner == nil {
+		return models.ScanResult{Status: models.ScanStatusUnscanned}, true
+	}
+
+	ctx := c.Request.Context()
+	result, err := h.scanner.Scan(ctx, r)
+	if err != nil {
+		metrics.UploadScans.Inc("error")
+		if h.scanFailOpen {
+			slog.WarnContext(ctx, "malware scan failed, accepting the file unscanned", "error", err)
+			return models.ScanResult{Status: models.ScanStatusUnscanned}, true
+		}
+		slog.ErrorContext(ctx, "malware scan failed, rejecting the upload", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Malware scanner is unavailable, please try again later", "code": "scan_unavailable"})
+		return models.ScanResult{}, false
+	}
+
+	if result.Infected {
+		metrics.UploadScans.Inc("infected")
+		return models.ScanResult{Status: models.ScanStatusQuarantined, Signature: result.Signature}, true
+	}
+	metrics.UploadScans.Inc("clean")
+	return models.ScanResult{Status: models.ScanStatusClean}, true
+}
+
+// rejectQuarantined records a quarantined upload in the audit trail, since
+// it may come from a compromised account, and answers 422
+func (h *CVHandler) rejectQuarantined(c *gin.Context, cvFile *models.CVFile) {
+	ctx := c.Request.Context()
+	slog.WarnContext(ctx, "malware found in uploaded CV", "version", cvFile.ID, "signature", cvFile.ScanSignature)
+	if err := h.audit.Record(ctx, &models.AuditEvent{
+		Action: storage.AuditCVQuarantined,
+		IP:     c.ClientIP(),
+		Actor:  c.GetString("username"),
+		Detail: fmt.Sprintf("version %d: %s", cvFile.ID, cvFile.ScanSignature),
+	}); err != nil {
+		slog.WarnContext(ctx, "failed to record audit event", "action", storage.AuditCVQuarantined, "error", err)
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":     "The malware scanner flagged the file; it was quarantined and is not served",
+		"code":      "malware_detected",
+		"signature": cvFile.ScanSignature,
+		"versionId": cvFile.ID,
+	})
+}
+
+// openNegotiatedCV opens the current CV in the language requested by the
+// client and writes an error response if there is none
+func (h *CVHandler) openNegotiatedCV(c *gin.Context) (*models.CVFile, io.ReadSeekCloser, bool) {
+	languages, err := h.cvStorage.CurrentLanguages(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get current CV"})
+		return nil, nil, false
+	}
+
+	language, ok := negotiateLanguage(c, languages, h.defaultLanguage)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No CV found"})
+		return nil, nil, false
+	}
+
+	// Get current CV metadata and open its content in the blob store
+	cvFile, content, err := h.cvStorage.OpenCurrentCV(c.Request.Context(), language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get current CV"})
+		return nil, nil, false
 	}
 
 	if cvFile == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No CV found"})
+		return nil, nil, false
+	}
+
+	// The chosen variant depends on the request's language preferences
+	c.Header("Vary", "Accept-Language")
+	c.Header("Content-Language", cvFile.Language)
+
+	return cvFile, content, true
+}
+
+// requirePublicAccess writes a 403 response if the CV is only available
+// through share links
+func (h *CVHandler) requirePublicAccess(c *gin.Context) bool {
+	public, err := h.settings.GetBool(c.Request.Context(), storage.SettingPublicAccess, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get access settings"})
+		return false
+	}
+	if !public {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The CV is only available through share links"})
+		return false
+	}
+	return true
+}
+
+// DownloadCV serves the current CV file (public endpoint)
+func (h *CVHandler) DownloadCV(c *gin.Context) {
+	if !h.requirePublicAccess(c) {
 		return
 	}
 
+	cvFile, content, ok := h.openNegotiatedCV(c)
+	if !ok {
+		return
+	}
+	defer content.Close()
+
 	// Set headers for file download
 	c.Header("Content-Description", "File Transfer")
 	c.Header("Content-Transfer-Encoding", "binary")
 	c.Header("Content-Disposition", "attachment; filename=Nenad_Mihajlovic_CV.pdf")
-	c.Header("Content-Type", cvFile.ContentType)
-	c.Header("Content-Length", strconv.FormatInt(cvFile.FileSize, 10))
 
-	// Serve the file data
-	c.Data(http.StatusOK, cvFile.ContentType, data)
+	// Stream the file data
+	serveCVFile(c, cvFile, content)
+	h.recorder.record(c, cvFile, storage.AccessDownload, nil)
 }
 
-
 // ViewCV serves the current CV for viewing in browser (public endpoint)
 func (h *CVHandler) ViewCV(c *gin.Context) {
-	// Get current CV from file storage with data
-	cvFile, data, err := h.cvStorage.GetCurrentCVWithData()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get current CV"})
+	if !h.requirePublicAccess(c) {
 		return
 	}
 
-	if cvFile == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No CV found"})
+	cvFile, content, ok := h.openNegotiatedCV(c)
+	if !ok {
 		return
 	}
+	defer content.Close()
 
-	// Set headers for inline viewing
-	c.Header("Content-Type", cvFile.ContentType)
-	c.Header("Content-Length", strconv.FormatInt(cvFile.FileSize, 10))
+	// Stream the file data for inline viewing
 	c.Header("Content-Disposition", "inline; filename=Nenad_Mihajlovic_CV.pdf")
-
-	// Serve the file data for viewing
-	c.Data(http.StatusOK, cvFile.ContentType, data)
+	serveCVFile(c, cvFile, content)
+	h.recorder.record(c, cvFile, storage.AccessView, nil)
 }
 
 // GetCVInfo returns information about the current CV (protected endpoint)
 func (h *CVHandler) GetCVInfo(c *gin.Context) {
-	cvFile, err := h.cvStorage.GetCurrentCV()
+	language, ok := h.queryLanguage(c)
+	if !ok {
+		return
+	}
+
+	languages, err := h.cvStorage.CurrentLanguages(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get current CV"})
+		return
+	}
+
+	cvFile, err := h.cvStorage.GetCurrentCV(c.Request.Context(), language)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get current CV"})
 		return
@@ -128,41 +389,215 @@ func (h *CVHandler) GetCVInfo(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"exists":        true,
+		"exists":       true,
 		"name":         cvFile.OriginalName,
 		"size":         cvFile.FileSize,
 		"lastModified": cvFile.UpdatedAt.Format("2006-01-02 15:04:05"),
 		"uploadedAt":   cvFile.CreatedAt.Format("2006-01-02 15:04:05"),
+		"language":     cvFile.Language,
+		"languages":    languages,
 	})
 }
 
+// DeleteCV deletes the current CV file (protected endpoint).
+// By default the version is soft-deleted and can be restored; pass
+// ?permanent=true to remove it and its content for good. Without ?lang=
+// the current CV of every language is deleted.
+func (h *CVHandler) DeleteCV(c *gin.Context) {
+	permanent := c.Query("permanent") == "true"
 
+	language := ""
+	if c.Query("lang") != "" {
+		var ok bool
+		if language, ok = h.queryLanguage(c); !ok {
+			return
+		}
+	}
 
-// DeleteCV deletes the current CV file (protected endpoint)
-func (h *CVHandler) DeleteCV(c *gin.Context) {
-	err := h.cvStorage.DeleteCV()
+	err := h.cvStorage.DeleteCV(c.Request.Context(), language, permanent)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "CV deleted successfully",
+		"permanent": permanent,
+	})
+}
+
+// ListVersions returns the upload history of the CV (protected endpoint)
+func (h *CVHandler) ListVersions(c *gin.Context) {
+	versions, err := h.cvStorage.ListVersions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list CV versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"versions": versions,
+		"count":    len(versions),
+	})
+}
+
+// DownloadVersion serves the file of a specific CV version (protected endpoint)
+func (h *CVHandler) DownloadVersion(c *gin.Context) {
+	id, ok := parseVersionID(c)
+	if !ok {
+		return
+	}
+
+	cvFile, content, err := h.cvStorage.OpenVersion(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrCVVersionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "CV version not found"})
+			return
+		}
+		if errors.Is(err, storage.ErrCVVersionQuarantined) {
+			c.JSON(http.StatusConflict, gin.H{"error": "CV version is quarantined", "code": "cv_quarantined"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get CV version"})
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": cvFile.OriginalName}))
+	serveCVFile(c, cvFile, content)
+}
+
+// RestoreVersion makes a previous CV version current again (protected endpoint)
+func (h *CVHandler) RestoreVersion(c *gin.Context) {
+	id, ok := parseVersionID(c)
+	if !ok {
+		return
+	}
+
+	cvFile, err := h.cvStorage.RestoreVersion(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrCVVersionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "CV version not found"})
+			return
+		}
+		if errors.Is(err, storage.ErrCVVersionQuarantined) {
+			c.JSON(http.StatusConflict, gin.H{"error": "CV version is quarantined", "code": "cv_quarantined"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore CV version"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "CV deleted successfully",
+		"message": "CV version restored successfully",
+		"version": cvFile,
 	})
 }
 
+// RollbackCV restores the version uploaded before the current one (protected endpoint)
+func (h *CVHandler) RollbackCV(c *gin.Context) {
+	language, ok := h.queryLanguage(c)
+	if !ok {
+		return
+	}
+
+	cvFile, err := h.cvStorage.RollbackCV(c.Request.Context(), language)
+	if err != nil {
+		if errors.Is(err, storage.ErrCVVersionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No previous CV version to roll back to"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to roll back CV"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "CV rolled back successfully",
+		"version": cvFile,
+	})
+}
+
+// serveCVFile streams a CV file with support for Range requests and conditional
+// GETs. The ETag is derived from the content hash and Last-Modified from the
+// row's UpdatedAt, so unchanged files are revalidated with a 304. The hash is
+// also sent as a Repr-Digest header (RFC 9530), so clients can verify the
+// complete file, even when they fetched it in ranges.
+func serveCVFile(c *gin.Context, cvFile *models.CVFile, content io.ReadSeeker) {
+	c.Header("ETag", cvFileETag(cvFile))
+	if cvFile.SHA256 != "" {
+		if digest, err := hex.DecodeString(cvFile.SHA256); err == nil {
+			c.Header("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(digest)+":")
+		}
+	}
+	c.Header("Content-Type", cvFile.ContentType)
+	c.Header("Cache-Control", "no-cache")
+
+	// A span of its own separates the time spent sending the file from the
+	// queries that found it
+	_, span := tracing.Tracer().Start(c.Request.Context(), "serve CV file",
+		trace.WithAttributes(attribute.Int("cv.version", int(cvFile.ID))))
+	defer span.End()
+
+	http.ServeContent(c.Writer, c.Request, cvFile.OriginalName, cvFile.UpdatedAt, content)
+	span.SetAttributes(attribute.Int("http.response.body.size", c.Writer.Size()))
+}
+
+// cvFileETag derives the ETag of a CV file from its content hash, or from its
+// ID and modification time for files stored before hashes were recorded
+func cvFileETag(cvFile *models.CVFile) string {
+	if cvFile.SHA256 != "" {
+		return `"` + cvFile.SHA256 + `"`
+	}
+	return fmt.Sprintf(`W/"%d-%d"`, cvFile.ID, cvFile.UpdatedAt.Unix())
+}
+
+// queryLanguage returns the ?lang= parameter or the default language and
+// writes a 400 response if the tag is malformed
+func (h *CVHandler) queryLanguage(c *gin.Context) (string, bool) {
+	lang := c.Query("lang")
+	if lang == "" {
+		return h.defaultLanguage, true
+	}
+
+	language, ok := normalizeLanguage(lang)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language tag"})
+		return "", false
+	}
+	return language, true
+}
+
+// parseVersionID reads the :id path parameter and writes a 400 response if it is invalid
+func parseVersionID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
 // GetStats returns statistics about CV files (protected endpoint)
 func (h *CVHandler) GetStats(c *gin.Context) {
-	fileCount, totalSize, err := h.cvStorage.GetStats()
+	fileCount, totalSize, err := h.cvStorage.GetStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get statistics"})
+		return
+	}
+
+	downloads, views, err := h.analytics.AccessTotals(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get statistics"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"fileCount": fileCount,
-		"totalSize": totalSize,
+		"fileCount":   fileCount,
+		"totalSize":   totalSize,
 		"totalSizeMB": float64(totalSize) / 1024 / 1024,
+		"downloads":   downloads,
+		"views":       views,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"cv-backend/internal/pdfgen"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublishGeneratedRequest selects what to render when publishing a generated CV
+type PublishGeneratedRequest struct {
+	Lang     string `json:"lang"`
+	Template string `json:"template"`
+	Note     string `json:"note"`
+}
+
+// renderCV renders the visible CV content and writes an error response on failure
+func (h *CVHandler) renderCV(c *gin.Context, language, templateName string) ([]byte, bool) {
+	tpl, ok := pdfgen.LookupTemplate(templateName)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Unknown template",
+			"templates": pdfgen.Templates(),
+		})
+		return nil, false
+	}
+
+	content, err := h.contentStorage.PublicContent(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get CV content"})
+		return nil, false
+	}
+
+	pdf, err := pdfgen.Render(content, pdfgen.Options{
+		Template:        tpl,
+		Language:        language,
+		DefaultLanguage: h.defaultLanguage,
+		Name:            h.ownerName,
+		Headline:        h.ownerHeadline,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate CV"})
+		return nil, false
+	}
+
+	return pdf, true
+}
+
+// GeneratedCV renders the CV content as a PDF (public endpoint).
+// ?lang= and ?template= select the language and layout.
+func (h *CVHandler) GeneratedCV(c *gin.Context) {
+	if !h.requirePublicAccess(c) {
+		return
+	}
+
+	language := h.defaultLanguage
+	if lang, ok := normalizeLanguage(c.Query("lang")); ok {
+		language = lang
+	} else if preferred := parseAcceptLanguage(c.GetHeader("Accept-Language")); len(preferred) > 0 {
+		language = baseLanguage(preferred[0])
+	}
+
+	pdf, ok := h.renderCV(c, language, c.Query("template"))
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{
+		"filename": pdfgen.Filename(h.ownerName, language),
+	}))
+	c.Header("Content-Language", language)
+	c.Header("Vary", "Accept-Language")
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// PublishGeneratedCV renders the CV content and stores the PDF as a new
+// current CV version for its language, unless it equals the current version
+// (protected endpoint)
+func (h *CVHandler) PublishGeneratedCV(c *gin.Context) {
+	var req PublishGeneratedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	language := h.defaultLanguage
+	if req.Lang != "" {
+		normalized, ok := normalizeLanguage(req.Lang)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language tag"})
+			return
+		}
+		language = normalized
+	}
+
+	templateName := req.Template
+	if templateName == "" {
+		templateName = pdfgen.DefaultTemplate
+	}
+
+	pdf, ok := h.renderCV(c, language, templateName)
+	if !ok {
+		return
+	}
+
+	// Only a note given by the client conflicts with an unchanged CV
+	changeNote := strings.TrimSpace(req.Note)
+
+	sum := sha256.Sum256(pdf)
+	digest := hex.EncodeToString(sum[:])
+	cvFile, ok := h.unchangedCV(c, language, digest, changeNote)
+	if !ok {
+		return
+	}
+	unchanged := cvFile != nil
+	message := "Generated CV unchanged from current version"
+
+	if !unchanged {
+		if changeNote == "" {
+			changeNote = fmt.Sprintf("Generated from CV content (%s template)", templateName)
+		}
+
+		// Generated files go through the scanner like uploads
+		scan, ok := h.scanCV(c, bytes.NewReader(pdf))
+		if !ok {
+			return
+		}
+
+		var err error
+		cvFile, err = h.cvStorage.UploadCV(c.Request.Context(), bytes.NewReader(pdf), pdfgen.Filename(h.ownerName, language), int64(len(pdf)), digest, "application/pdf", language, changeNote, scan)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save CV: %v", err)})
+			return
+		}
+		if cvFile.Quarantined() {
+			h.rejectQuarantined(c, cvFile)
+			return
+		}
+		message = "Generated CV published successfully"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"message":      message,
+		"unchanged":    unchanged,
+		"sha256":       cvFile.SHA256,
+		"versionId":    cvFile.ID,
+		"language":     cvFile.Language,
+		"template":     templateName,
+		"originalName": cvFile.OriginalName,
+		"size":         cvFile.FileSize,
+		"uploadedAt":   cvFile.CreatedAt,
+	})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"cv-backend/internal/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Livez reports that the process is up and serving requests. It does not
+// check dependencies, so an outage of the database does not restart it.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "cv-backend"})
+}
+
+// Readyz pings the database and the blob store and reports each check.
+// It answers 503 while a check fails, migrations run or the server shuts down.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
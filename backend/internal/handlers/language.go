@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var languageTagPattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// normalizeLanguage lowercases a BCP 47 language tag and reports whether it is well-formed
+func normalizeLanguage(tag string) (string, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	return tag, languageTagPattern.MatchString(tag)
+}
+
+// baseLanguage returns the primary subtag of a language tag ("de-at" -> "de")
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
+
+// matchLanguage finds the available language that best matches the requested tag
+func matchLanguage(requested string, available []string) (string, bool) {
+	for _, lang := range available {
+		if lang == requested {
+			return lang, true
+		}
+	}
+	for _, lang := range available {
+		if baseLanguage(lang) == baseLanguage(requested) {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header ordered by quality
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		tag, ok := normalizeLanguage(tag)
+		if !ok || q <= 0 {
+			continue
+		}
+		entries = append(entries, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+
+	tags := make([]string, len(entries))
+	for i, entry := range entries {
+		tags[i] = entry.tag
+	}
+	return tags
+}
+
+// negotiateLanguage picks a CV language from ?lang=, then Accept-Language,
+// then the configured default, and otherwise any available language, so a CV
+// is served as long as one exists. It returns false if none is available.
+func negotiateLanguage(c *gin.Context, available []string, defaultLanguage string) (string, bool) {
+	if requested, ok := normalizeLanguage(c.Query("lang")); ok {
+		if lang, found := matchLanguage(requested, available); found {
+			return lang, true
+		}
+	}
+
+	for _, requested := range parseAcceptLanguage(c.GetHeader("Accept-Language")) {
+		if lang, found := matchLanguage(requested, available); found {
+			return lang, true
+		}
+	}
+
+	for _, lang := range available {
+		if lang == defaultLanguage {
+			return lang, true
+		}
+	}
+	if len(available) > 0 {
+		return available[0], true
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"cv-backend/internal/models"
+	"cv-backend/internal/storage"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListLockouts returns the accounts and client IPs that are currently locked out (protected endpoint)
+func (h *AuthHandler) ListLockouts(c *gin.Context) {
+	users, err := h.throttle.LockedUsers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list lockouts"})
+		return
+	}
+
+	ips, err := h.throttle.BlockedIPs(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list lockouts"})
+		return
+	}
+
+	accounts := make([]gin.H, 0, len(users))
+	for _, user := range users {
+		accounts = append(accounts, gin.H{
+			"username":           user.Username,
+			"failed_login_count": user.FailedLoginCount,
+			"locked_until":       user.LockedUntil,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": accounts,
+		"ips":      ips,
+	})
+}
+
+// ClearLockout unlocks an account and/or a client IP (protected endpoint)
+func (h *AuthHandler) ClearLockout(c *gin.Context) {
+	var req models.ClearLockoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if req.Username == "" && req.IP == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username or ip is required"})
+		return
+	}
+	if req.IP != "" && net.ParseIP(req.IP) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+
+	actor := c.GetString("username")
+
+	if req.Username != "" {
+		if err := h.throttle.UnlockUser(c.Request.Context(), req.Username, actor); err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+			return
+		}
+	}
+
+	if req.IP != "" {
+		if err := h.throttle.UnblockIP(c.Request.Context(), req.IP, actor); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock IP"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Lockout cleared",
+	})
+}
+
+// ListAuditEvents returns the most recent security events (protected endpoint)
+func (h *AuthHandler) ListAuditEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	events, err := h.audit.ListEvents(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"count":  len(events),
+	})
+}
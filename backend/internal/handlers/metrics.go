@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"cv-backend/internal/config"
+	"cv-backend/internal/metrics"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetricsHandler struct {
+	token string
+}
+
+func NewMetricsHandler(cfg *config.Config) *MetricsHandler {
+	return &MetricsHandler{token: cfg.MetricsToken}
+}
+
+// Metrics writes all metrics in the Prometheus text format. With
+// METRICS_TOKEN set, scrapers must send it as a bearer token.
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	if h.token != "" {
+		expected := "Bearer " + h.token
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	if err := metrics.Default.Write(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
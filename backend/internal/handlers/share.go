@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"cv-backend/internal/models"
+	"cv-backend/internal/pdfgen"
+	"cv-backend/internal/storage"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateShareLinkRequest describes a new share link. Without versionId the
+// link follows the current CV; lang pins its language.
+type CreateShareLinkRequest struct {
+	Label        string     `json:"label"`
+	VersionID    *uint      `json:"versionId"`
+	Lang         string     `json:"lang"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+	MaxDownloads int        `json:"maxDownloads"`
+	Password     string     `json:"password"`
+}
+
+// ShareLinkResponse is a share link as shown to admins
+type ShareLinkResponse struct {
+	models.ShareLink
+	Status            string `json:"status"`
+	PasswordProtected bool   `json:"passwordProtected"`
+	Path              string `json:"path"`
+}
+
+// minShareLinkPasswordLength is the shortest password accepted for a share
+// link. The unlock endpoints are public, so short passwords are guessable
+// despite the throttle.
+const minShareLinkPasswordLength = 8
+
+// PublicAccessRequest turns access without a share link on or off
+type PublicAccessRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+func newShareLinkResponse(link models.ShareLink) ShareLinkResponse {
+	return ShareLinkResponse{
+		ShareLink:         link,
+		Status:            link.Status(time.Now()),
+		PasswordProtected: link.PasswordHash != "",
+		Path:              "/api/share/" + link.Token,
+	}
+}
+
+// CreateShareLink mints a new share link (protected endpoint)
+func (h *CVHandler) CreateShareLink(c *gin.Context) {
+	var req CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	link := models.ShareLink{
+		Label:        strings.TrimSpace(req.Label),
+		MaxDownloads: req.MaxDownloads,
+		ExpiresAt:    req.ExpiresAt,
+	}
+
+	if req.MaxDownloads < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "maxDownloads must not be negative"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+		return
+	}
+	if req.Password != "" && len(req.Password) < minShareLinkPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at least %d characters long", minShareLinkPasswordLength)})
+		return
+	}
+
+	if req.VersionID != nil {
+		if req.Lang != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "lang can only be set for links to the current CV"})
+			return
+		}
+		cvFile, err := h.cvStorage.GetVersion(c.Request.Context(), *req.VersionID)
+		if err != nil {
+			if errors.Is(err, storage.ErrCVVersionNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "CV version not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get CV version"})
+			return
+		}
+		if cvFile.DeletedAt.Valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot share a deleted CV version"})
+			return
+		}
+		if cvFile.Quarantined() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot share a quarantined CV version"})
+			return
+		}
+		link.CVFileID = &cvFile.ID
+	} else if req.Lang != "" {
+		language, ok := normalizeLanguage(req.Lang)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language tag"})
+			return
+		}
+		link.Language = language
+	}
+
+	if err := h.shareLinks.CreateShareLink(c.Request.Context(), &link, req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newShareLinkResponse(link))
+}
+
+// ListShareLinks returns all share links (protected endpoint)
+func (h *CVHandler) ListShareLinks(c *gin.Context) {
+	links, err := h.shareLinks.ListShareLinks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list share links"})
+		return
+	}
+
+	response := make([]ShareLinkResponse, 0, len(links))
+	for _, link := range links {
+		response = append(response, newShareLinkResponse(link))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shareLinks": response,
+		"count":      len(response),
+	})
+}
+
+// RevokeShareLink disables a share link (protected endpoint)
+func (h *CVHandler) RevokeShareLink(c *gin.Context) {
+	id, ok := parseShareLinkID(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.shareLinks.RevokeShareLink(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrShareLinkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "Share link revoked",
+		"shareLink": newShareLinkResponse(*link),
+	})
+}
+
+// ShareLinkAccesses returns the access log of a share link (protected endpoint)
+func (h *CVHandler) ShareLinkAccesses(c *gin.Context) {
+	id, ok := parseShareLinkID(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.shareLinks.GetShareLink(c.Request.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrShareLinkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get share link"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	events, err := h.shareLinks.ListAccesses(c.Request.Context(), id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get share link accesses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accesses": events,
+		"count":    len(events),
+	})
+}
+
+// GetPublicAccess reports whether the CV can be fetched without a share link (protected endpoint)
+func (h *CVHandler) GetPublicAccess(c *gin.Context) {
+	public, err := h.settings.GetBool(c.Request.Context(), storage.SettingPublicAccess, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get access settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": public})
+}
+
+// SetPublicAccess turns access without a share link on or off (protected endpoint)
+func (h *CVHandler) SetPublicAccess(c *gin.Context) {
+	var req PublicAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if err := h.settings.SetBool(c.Request.Context(), storage.SettingPublicAccess, *req.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save access settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"enabled": *req.Enabled,
+	})
+}
+
+// lookupShareLink finds the link of the :token parameter and writes an error
+// response if it does not exist or can no longer be used
+func (h *CVHandler) lookupShareLink(c *gin.Context) (*models.ShareLink, bool) {
+	link, err := h.shareLinks.GetShareLinkByToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if errors.Is(err, storage.ErrShareLinkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get share link"})
+		return nil, false
+	}
+
+	if status := link.Status(time.Now()); status != models.ShareLinkActive {
+		c.JSON(http.StatusGone, gin.H{
+			"error":  "This share link is no longer available",
+			"status": status,
+		})
+		return nil, false
+	}
+
+	return link, true
+}
+
+// unlockShareLink checks the password sent in the X-Share-Password header
+// and writes a 401 response if it does not match. Wrong passwords are
+// throttled per link and per client IP; refused attempts get a 429.
+func (h *CVHandler) unlockShareLink(c *gin.Context, link *models.ShareLink) bool {
+	if link.PasswordHash == "" {
+		return true
+	}
+
+	password := c.GetHeader("X-Share-Password")
+	if password == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password required", "passwordRequired": true})
+		return false
+	}
+
+	wait, err := h.throttle.IPRetryAfter(c.Request.Context(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password attempts"})
+		return false
+	}
+	if link.UnlockBlockedUntil != nil {
+		wait = max(wait, time.Until(*link.UnlockBlockedUntil))
+	}
+	if wait > 0 {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many failed password attempts, please try again later",
+			"retry_after": retryAfter,
+		})
+		return false
+	}
+
+	if !h.shareLinks.CheckPassword(link, password) {
+		if err := h.shareLinks.RecordUnlockFailure(c.Request.Context(), link); err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to record share link password failure", "error", err)
+		}
+		if err := h.throttle.RecordIPFailure(c.Request.Context(), c.ClientIP()); err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to record share link password failure", "error", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password", "passwordRequired": true})
+		return false
+	}
+
+	if err := h.shareLinks.RecordUnlockSuccess(c.Request.Context(), link); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to reset share link password failures", "error", err)
+	}
+	return true
+}
+
+// ShareLinkInfo describes a share link to its recipient before downloading (public endpoint)
+func (h *CVHandler) ShareLinkInfo(c *gin.Context) {
+	link, ok := h.lookupShareLink(c)
+	if !ok {
+		return
+	}
+
+	var remaining *int
+	if link.MaxDownloads > 0 {
+		left := link.MaxDownloads - link.DownloadCount
+		remaining = &left
+	}
+
+	c.Header("X-Robots-Tag", "noindex")
+	c.JSON(http.StatusOK, gin.H{
+		"passwordRequired":   link.PasswordHash != "",
+		"expiresAt":          link.ExpiresAt,
+		"remainingDownloads": remaining,
+	})
+}
+
+// ShareLinkDownload serves the CV of a share link as an attachment (public endpoint)
+func (h *CVHandler) ShareLinkDownload(c *gin.Context) {
+	h.serveShareLink(c, "attachment", storage.AccessDownload)
+}
+
+// ShareLinkView serves the CV of a share link for viewing in the browser (public endpoint)
+func (h *CVHandler) ShareLinkView(c *gin.Context) {
+	h.serveShareLink(c, "inline", storage.AccessView)
+}
+
+func (h *CVHandler) serveShareLink(c *gin.Context, disposition, kind string) {
+	link, ok := h.lookupShareLink(c)
+	if !ok || !h.unlockShareLink(c, link) {
+		return
+	}
+
+	cvFile, content, ok := h.openShareLinkCV(c, link)
+	if !ok {
+		return
+	}
+	defer content.Close()
+
+	// Every request that fetches the file counts towards the download cap,
+	// including views, but revalidations and Range chunks of a download in
+	// progress do not
+	if fetchesFile(c, cvFile) {
+		if err := h.shareLinks.ClaimDownload(c.Request.Context(), link); err != nil {
+			if errors.Is(err, storage.ErrShareLinkExhausted) {
+				c.JSON(http.StatusGone, gin.H{
+					"error":  "This share link is no longer available",
+					"status": models.ShareLinkExhausted,
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get share link"})
+			return
+		}
+	}
+
+	c.Header("X-Robots-Tag", "noindex")
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+		"filename": pdfgen.Filename(h.ownerName, cvFile.Language),
+	}))
+	serveCVFile(c, cvFile, content)
+	h.recorder.record(c, cvFile, kind, link)
+}
+
+// fetchesFile reports whether serving cvFile will send its content: a GET
+// that is not answered with 304 Not Modified, following the precedence of
+// http.ServeContent. A Range request only continues an earlier download when
+// its If-Range validator matches the file; without one it is a new fetch, so
+// requesting the file in pieces cannot get around the download cap.
+func fetchesFile(c *gin.Context, cvFile *models.CVFile) bool {
+	if c.Request.Method != http.MethodGet || notModified(c, cvFile) {
+		return false
+	}
+	return c.GetHeader("Range") == "" || !ifRangeMatches(c, cvFile)
+}
+
+// notModified reports whether the request's conditional headers make
+// http.ServeContent answer 304 Not Modified
+func notModified(c *gin.Context, cvFile *models.CVFile) bool {
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		etag := strings.TrimPrefix(cvFileETag(cvFile), "W/")
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	modifiedSince, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	return err == nil && !cvFile.UpdatedAt.Truncate(time.Second).After(modifiedSince)
+}
+
+// ifRangeMatches reports whether the If-Range header names the current file,
+// in which case http.ServeContent honours the Range header. Like
+// http.ServeContent it only accepts a strong ETag or the exact modification
+// time.
+func ifRangeMatches(c *gin.Context, cvFile *models.CVFile) bool {
+	ifRange := c.GetHeader("If-Range")
+	if ifRange == "" {
+		return false
+	}
+	if strings.HasPrefix(ifRange, `"`) {
+		return ifRange == cvFileETag(cvFile)
+	}
+	modified, err := http.ParseTime(ifRange)
+	return err == nil && modified.Unix() == cvFile.UpdatedAt.Unix()
+}
+
+// openShareLinkCV opens the version a share link points to, or the current
+// CV in the link's language, and writes an error response if it is gone
+func (h *CVHandler) openShareLinkCV(c *gin.Context, link *models.ShareLink) (*models.CVFile, io.ReadSeekCloser, bool) {
+	if link.CVFileID == nil && link.Language == "" {
+		return h.openNegotiatedCV(c)
+	}
+
+	if link.CVFileID == nil {
+		cvFile, content, err := h.cvStorage.OpenCurrentCV(c.Request.Context(), link.Language)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get current CV"})
+			return nil, nil, false
+		}
+		if cvFile == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No CV found"})
+			return nil, nil, false
+		}
+		c.Header("Content-Language", cvFile.Language)
+		return cvFile, content, true
+	}
+
+	cvFile, content, err := h.cvStorage.OpenVersion(c.Request.Context(), *link.CVFileID)
+	if err != nil {
+		if errors.Is(err, storage.ErrCVVersionNotFound) || errors.Is(err, storage.ErrCVVersionQuarantined) {
+			c.JSON(http.StatusGone, gin.H{"error": "This CV version is no longer available"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get CV version"})
+		return nil, nil, false
+	}
+	if cvFile.DeletedAt.Valid {
+		content.Close()
+		c.JSON(http.StatusGone, gin.H{"error": "This CV version is no longer available"})
+		return nil, nil, false
+	}
+	c.Header("Content-Language", cvFile.Language)
+	return cvFile, content, true
+}
+
+// parseShareLinkID reads the :id path parameter and writes a 400 response if it is invalid
+func parseShareLinkID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
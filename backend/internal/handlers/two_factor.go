@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"cv-backend/internal/auth"
+	"cv-backend/internal/models"
+	"cv-backend/internal/storage"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser loads the authenticated user and writes an error response if that fails
+func (h *AuthHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	user, err := h.userStorage.GetUser(c.Request.Context(), username.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+		return nil, false
+	}
+	return user, true
+}
+
+// LoginTwoFactor completes a login with a TOTP or recovery code
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var loginReq models.LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&loginReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	claims, err := auth.ValidateChallengeToken(loginReq.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please sign in again"})
+		return
+	}
+
+	user, err := h.userStorage.GetUser(c.Request.Context(), claims.Username)
+	if err != nil || user.TokenVersion != claims.TokenVersion || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired, please sign in again"})
+		return
+	}
+
+	// Wrong codes count towards the lockout like wrong passwords
+	if !h.allowLoginAttempt(c, user.Username) {
+		return
+	}
+	if err := h.twoFactor.VerifyCode(c.Request.Context(), user, loginReq.Code); err != nil {
+		if errors.Is(err, storage.ErrInvalidTwoFactorCode) {
+			h.recordLoginFailure(c, user.Username)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		return
+	}
+	h.recordLoginSuccess(c, user.Username)
+
+	token, refreshToken, err := h.issueTokens(c, user, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL().Seconds()),
+		Message:      "Login successful",
+		FirstLogin:   user.FirstLogin,
+	})
+}
+
+// TwoFactorStatus reports whether 2FA is enabled and how many recovery codes are left
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	remaining, err := h.twoFactor.RemainingRecoveryCodes(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// EnrollTwoFactor creates a new TOTP secret. 2FA stays off until the secret
+// is confirmed with a code at /api/2fa/activate. It requires the current
+// password, so a hijacked session cannot enroll an authenticator of its own.
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	var req models.EnrollTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	if !h.verifyPassword(c, user.Username, req.CurrentPassword) {
+		return
+	}
+
+	secret, err := h.twoFactor.Enroll(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(h.totpIssuer, user.Username, secret),
+	})
+}
+
+// ActivateTwoFactor turns on 2FA once a code from the enrolled authenticator
+// and the current password are confirmed and returns the recovery codes,
+// which are only shown once
+func (h *AuthHandler) ActivateTwoFactor(c *gin.Context) {
+	var req models.ActivateTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	if !h.verifyPassword(c, user.Username, req.CurrentPassword) {
+		return
+	}
+
+	codes, err := h.twoFactor.Activate(c.Request.Context(), user, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTwoFactorNotEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Enroll two-factor authentication first"})
+		case errors.Is(err, storage.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns off 2FA; it requires the current password and a code
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	// The failure count is only reset once both the password and the code
+	// have passed, so neither can be guessed between correct answers
+	if !h.checkPassword(c, user.Username, req.Password) {
+		return
+	}
+	if !h.verifyCode(c, user, req.Code) {
+		return
+	}
+	h.resetFailures(c, user.Username)
+
+	if err := h.twoFactor.Disable(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes; it requires a code
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if !h.verifyCode(c, user, req.Code) {
+		return
+	}
+	h.resetFailures(c, user.Username)
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"recovery_codes": codes,
+	})
+}
+
+// verifyCode checks a TOTP or recovery code and writes an error response if
+// it is invalid. Wrong codes count towards the login lockout, so a stolen
+// access token cannot be used to guess codes; the caller resets the count
+// once all checks have passed.
+func (h *AuthHandler) verifyCode(c *gin.Context, user *models.User, code string) bool {
+	if !h.allowLoginAttempt(c, user.Username) {
+		return false
+	}
+	if err := h.twoFactor.VerifyCode(c.Request.Context(), user, code); err != nil {
+		if errors.Is(err, storage.ErrInvalidTwoFactorCode) {
+			h.recordLoginFailure(c, user.Username)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"cv-backend/internal/models"
+	"cv-backend/internal/storage"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,64}$`)
+
+// ListUsers returns all users (owner only)
+func (h *AuthHandler) ListUsers(c *gin.Context) {
+	users, err := h.userStorage.ListUsers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+// CreateUser adds a user with an initial password they must change on first login (owner only)
+func (h *AuthHandler) CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if !usernamePattern.MatchString(req.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username must be 3-64 letters, digits, dots, dashes or underscores"})
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of owner, editor or viewer"})
+		return
+	}
+	if len(req.Password) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
+		return
+	}
+
+	user, err := h.userStorage.CreateUser(c.Request.Context(), req.Username, req.Password, req.Role)
+	if err != nil {
+		if errors.Is(err, storage.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	h.recordUserAudit(c, storage.AuditUserCreated, user.Username, "role="+user.Role)
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUser changes the role of a user and/or resets their password (owner only)
+func (h *AuthHandler) UpdateUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if req.Role == "" && req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role or password is required"})
+		return
+	}
+	if req.Role != "" && !models.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of owner, editor or viewer"})
+		return
+	}
+	if req.Password != "" && len(req.Password) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
+		return
+	}
+
+	var changes []string
+	if req.Role != "" {
+		if err := h.userStorage.SetRole(c.Request.Context(), id, req.Role); err != nil {
+			writeUserError(c, err, "Failed to update user")
+			return
+		}
+		changes = append(changes, "role="+req.Role)
+	}
+	if req.Password != "" {
+		if err := h.userStorage.ResetPassword(c.Request.Context(), id, req.Password); err != nil {
+			writeUserError(c, err, "Failed to update user")
+			return
+		}
+		// End the user's sessions along with the old password
+		if err := h.refreshTokens.RevokeAllForUser(c.Request.Context(), id); err != nil {
+			writeUserError(c, err, "Failed to update user")
+			return
+		}
+		changes = append(changes, "password reset")
+	}
+
+	user, err := h.userStorage.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		writeUserError(c, err, "Failed to get user info")
+		return
+	}
+
+	h.recordUserAudit(c, storage.AuditUserUpdated, user.Username, strings.Join(changes, ", "))
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser removes a user and ends their sessions (owner only)
+func (h *AuthHandler) DeleteUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userStorage.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		writeUserError(c, err, "Failed to delete user")
+		return
+	}
+
+	if user.Username == c.GetString("username") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete your own account"})
+		return
+	}
+
+	if err := h.userStorage.DeleteUser(c.Request.Context(), id); err != nil {
+		writeUserError(c, err, "Failed to delete user")
+		return
+	}
+
+	h.recordUserAudit(c, storage.AuditUserDeleted, user.Username, "")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "User deleted successfully",
+	})
+}
+
+func (h *AuthHandler) recordUserAudit(c *gin.Context, action, username, detail string) {
+	if err := h.audit.Record(c.Request.Context(), &models.AuditEvent{
+		Action:   action,
+		Username: username,
+		IP:       c.ClientIP(),
+		Actor:    c.GetString("username"),
+		Detail:   detail,
+	}); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to record audit event", "action", action, "error", err)
+	}
+}
+
+func parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeUserError maps user storage errors to responses
+func writeUserError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, storage.ErrLastOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": "There must be at least one owner"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,120 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check is a named dependency check. Run must respect the context deadline.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Status    string  `json:"status"` // ok or failed
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the readiness of the service and the result of every check
+type Report struct {
+	Status string                 `json:"status"` // ready, not_ready, migrating or shutting_down
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Ready reports whether the service can take traffic
+func (r Report) Ready() bool {
+	return r.Status == "ready"
+}
+
+// Checker runs the readiness checks and tracks the startup and shutdown phases
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+
+	migrating    atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a Checker that gives each check at most timeout to finish
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// SetMigrating marks whether startup migrations are running
+func (c *Checker) SetMigrating(migrating bool) {
+	c.migrating.Store(migrating)
+}
+
+// Migrating reports whether startup migrations are still running
+func (c *Checker) Migrating() bool {
+	return c.migrating.Load()
+}
+
+// SetShuttingDown marks the service as shutting down; it stays not ready
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check runs all checks concurrently and reports the overall readiness
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Status: "ready", Checks: make(map[string]CheckResult, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status != "ok" {
+				report.Status = "not_ready"
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	// The phase takes precedence over the checks, which may pass while
+	// the schema is still being migrated or connections are draining
+	switch {
+	case c.shuttingDown.Load():
+		report.Status = "shutting_down"
+	case c.migrating.Load():
+		report.Status = "migrating"
+	}
+	return report
+}
+
+// run executes one check with the timeout; a check that does not return in
+// time is reported as failed without waiting for it
+func (c *Checker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check.Run(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: "ok", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+	}
+	return result
+}
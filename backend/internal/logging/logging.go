@@ -0,0 +1,105 @@
+// Package logging sets up the structured JSON logger and carries the request
+// ID of the current request in its context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the header a request ID is taken from and returned in
+const RequestIDHeader = "X-Request-ID"
+
+// redacted replaces the values of sensitive attributes
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are substrings of attribute keys whose values are never logged
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "cookie", "api_key", "apikey"}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context that carries the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" outside a request
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ParseLevel converts debug, info, warn or error to a slog level
+func ParseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// New creates a logger that writes JSON lines to w. Records logged with a
+// context get the request ID and trace ID of that context, and sensitive
+// attributes are redacted.
+func New(w io.Writer, level string) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       ParseLevel(level),
+		ReplaceAttr: redact,
+	})
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// Setup installs a logger from New as the default, which the log package
+// writes through as well
+func Setup(w io.Writer, level string) {
+	slog.SetDefault(New(w, level))
+}
+
+// redact hides the values of attributes whose key suggests a credential
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
+
+// IsSensitive reports whether values under key must not be logged
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextHandler adds the request ID and trace of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// Default is the registry served at /metrics
+var Default = NewRegistry()
+
+// SizeBuckets are the upload size buckets in bytes, up to the 10MB upload limit
+var SizeBuckets = []float64{10e3, 50e3, 100e3, 250e3, 500e3, 1e6, 2.5e6, 5e6, 10e6}
+
+var (
+	// HTTPRequests counts requests by method, route template and status code
+	HTTPRequests = Default.NewCounterVec("cv_http_requests_total",
+		"HTTP requests by method, route and status code.", "method", "route", "status")
+	// HTTPDuration measures request latency by method, route template and status code
+	HTTPDuration = Default.NewHistogramVec("cv_http_request_duration_seconds",
+		"HTTP request latency by method, route and status code.", DefBuckets, "method", "route", "status")
+
+	// Logins counts login attempts by result: success, failure or throttled
+	Logins = Default.NewCounterVec("cv_logins_total",
+		"Login attempts by result.", "result")
+
+	// CVAccesses counts CV downloads and views by kind and CV version ID
+	CVAccesses = Default.NewCounterVec("cv_accesses_total",
+		"CV downloads and views by kind and version.", "kind", "version")
+	// UploadSize measures the size of uploaded and published CV files
+	UploadSize = Default.NewHistogramVec("cv_upload_size_bytes",
+		"Size of uploaded CV files in bytes.", SizeBuckets)
+	// UploadScans counts malware scans of uploaded CV files by result:
+	// clean, infected or error
+	UploadScans = Default.NewCounterVec("cv_upload_scans_total",
+		"Malware scans of uploaded CV files by result.", "result")
+
+	// StorageDuration measures CV storage operations by operation and result (ok or error)
+	StorageDuration = Default.NewHistogramVec("cv_storage_operation_duration_seconds",
+		"CV storage operation latency by operation and result.", DefBuckets, "operation", "result")
+)
+
+var registerDBStats sync.Once
+
+// RegisterDBStats exposes the connection pool statistics of db. Only the
+// first call has an effect.
+func RegisterDBStats(db *sql.DB) {
+	registerDBStats.Do(func() {
+		Default.NewGaugeFunc("cv_db_max_open_connections", "Maximum number of open database connections.",
+			func() float64 { return float64(db.Stats().MaxOpenConnections) })
+		Default.NewGaugeFunc("cv_db_open_connections", "Open database connections, in use or idle.",
+			func() float64 { return float64(db.Stats().OpenConnections) })
+		Default.NewGaugeFunc("cv_db_in_use_connections", "Database connections currently in use.",
+			func() float64 { return float64(db.Stats().InUse) })
+		Default.NewGaugeFunc("cv_db_idle_connections", "Idle database connections.",
+			func() float64 { return float64(db.Stats().Idle) })
+		Default.NewCounterFunc("cv_db_wait_count_total", "Connections waited for because the pool was exhausted.",
+			func() float64 { return float64(db.Stats().WaitCount) })
+		Default.NewCounterFunc("cv_db_wait_duration_seconds_total", "Time spent waiting for a database connection.",
+			func() float64 { return db.Stats().WaitDuration.Seconds() })
+	})
+}
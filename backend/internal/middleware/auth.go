@@ -2,14 +2,35 @@ package middleware
 
 import (
 	"cv-backend/internal/auth"
+	"cv-backend/internal/models"
+	"cv-backend/internal/storage"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware() gin.HandlerFunc {
+// apiKeyRoutes lists the protected routes that accept API keys and the scope
+// each one needs. All other protected routes require a login.
+var apiKeyRoutes = map[string]string{
+	"POST /api/upload-cv":               models.ScopeCVUpload,
+	"POST /api/cv/generated/publish":    models.ScopeCVUpload,
+	"GET /api/cv-info":                  models.ScopeCVRead,
+	"GET /api/cv/versions":              models.ScopeCVRead,
+	"GET /api/cv/versions/:id/download": models.ScopeCVRead,
+	"GET /api/cv-stats":                 models.ScopeStatsRead,
+	"GET /api/analytics/timeseries":     models.ScopeStatsRead,
+	"GET /api/analytics/referrers":      models.ScopeStatsRead,
+	"GET /api/analytics/versions":       models.ScopeStatsRead,
+	"GET /api/content/:section":         models.ScopeContentRead,
+	"POST /api/content/import":          models.ScopeContentWrite,
+}
+
+// AuthMiddleware authenticates requests with either a JWT access token
+// ("Bearer <token>") or an API key ("ApiKey <key>"). Access tokens issued
+// before the user's token version was bumped are rejected.
+func AuthMiddleware(userStorage storage.UserStore, apiKeys *storage.APIKeyStorage) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -18,14 +39,19 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Extract token from "Bearer <token>" format
+		// Extract token from "Bearer <token>" or "ApiKey <key>" format
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
 			c.Abort()
 			return
 		}
 
+		if parts[0] == "ApiKey" {
+			authenticateAPIKey(c, apiKeys, parts[1])
+			return
+		}
+
 		tokenString := parts[1]
 		claims, err := auth.ValidateToken(tokenString)
 		if err != nil {
@@ -34,8 +60,64 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Tokens issued before a password change or "log out all sessions" are revoked
+		user, err := userStorage.GetUser(c.Request.Context(), claims.Username)
+		if err != nil || user.TokenVersion != claims.TokenVersion {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("username", claims.Username)
+		c.Set("role", user.Role)
+		c.Next()
+	}
+}
+
+// authenticateAPIKey lets a request with an API key through if the route
+// accepts API keys and the key has the scope it needs
+func authenticateAPIKey(c *gin.Context, apiKeys *storage.APIKeyStorage, key string) {
+	apiKey, user, err := apiKeys.Authenticate(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidAPIKey) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API key"})
+		}
+		c.Abort()
+		return
+	}
+
+	scope, ok := apiKeyRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an API key"})
+		c.Abort()
+		return
+	}
+	if !apiKey.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	// The key acts as its user, so the user's role still applies
+	c.Set("username", user.Username)
+	c.Set("role", user.Role)
+	c.Set("api_key_id", apiKey.ID)
+	c.Next()
+}
+
+// RequireRole rejects users whose role ranks below the given one. It must
+// run after AuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := models.User{Role: c.GetString("role")}
+		if !user.HasRole(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -44,16 +126,17 @@ func AuthMiddleware() gin.HandlerFunc {
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
-		
+
 		// Allow all origins in development, specific origins in production
 		if origin != "" {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 		} else {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		}
-		
+
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Share-Password, X-Request-ID, traceparent, tracestate")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Repr-Digest")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -63,4 +146,4 @@ func CORSMiddleware() gin.HandlerFunc {
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"cv-backend/internal/logging"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDPattern limits accepted request IDs to a safe length and alphabet,
+// so that they can be echoed in headers, logs and JSON without escaping
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware takes the request ID from the X-Request-ID header or
+// generates one. The ID is returned in the response header, stored in the
+// request context for logging and added to JSON error responses.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(logging.RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(logging.RequestIDHeader, id)
+		c.Writer = &requestIDWriter{ResponseWriter: c.Writer, id: id}
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestIDWriter adds a request_id field to JSON error bodies, so that users
+// can quote it when reporting a failure
+type requestIDWriter struct {
+	gin.ResponseWriter
+	id      string
+	written bool
+}
+
+func (w *requestIDWriter) Write(b []byte) (int, error) {
+	first := !w.written
+	w.written = true
+	if !first || w.Status() < http.StatusBadRequest || len(b) < 2 || b[0] != '{' ||
+		!strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(b)
+	}
+
+	field := `{"request_id":"` + w.id + `"`
+	if b[1] != '}' {
+		field += ","
+	}
+	if _, err := w.ResponseWriter.Write(append([]byte(field), b[1:]...)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// LoggerMiddleware writes one log line per request. Server errors are logged
+// as errors and client errors as warnings. Path parameters and query
+// parameters that carry credentials, such as share link tokens, are redacted.
+func LoggerMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", redactedPath(c)),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if query := redactedQuery(c.Request.URL.Query()); query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if username := c.GetString("username"); username != "" {
+			attrs = append(attrs, slog.String("user", username))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// redactedPath returns the request path with sensitive path parameters hidden
+func redactedPath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	for _, param := range c.Params {
+		if param.Value != "" && logging.IsSensitive(param.Key) {
+			path = strings.Replace(path, "/"+param.Value, "/[REDACTED]", 1)
+		}
+	}
+	return path
+}
+
+// redactedQuery encodes the query with the values of sensitive parameters hidden
+func redactedQuery(query url.Values) string {
+	for key := range query {
+		if logging.IsSensitive(key) {
+			query[key] = []string{"[REDACTED]"}
+		}
+	}
+	return strings.ReplaceAll(query.Encode(), url.QueryEscape("[REDACTED]"), "[REDACTED]")
+}
+
+// RecoveryMiddleware turns a panic in a handler into a 500 response and logs
+// it with the stack trace and request ID
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				slog.ErrorContext(c.Request.Context(), "panic while handling request",
+					"error", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+				if !c.Writer.Written() {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				} else {
+					c.Abort()
+				}
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"cv-backend/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware counts requests and measures their latency per route
+// template and status code. Requests that match no route share one label so
+// that scanners cannot create unbounded series.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.Inc(c.Request.Method, route, status)
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, status)
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"cv-backend/internal/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartupMiddleware answers 503 while the startup migrations run, so that no
+// request reaches a schema or default user that is not there yet. The probes
+// are registered outside of it and keep answering.
+func StartupMiddleware(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if checker.Migrating() {
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The server is starting up, try again shortly", "code": "starting"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
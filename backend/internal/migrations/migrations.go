@@ -0,0 +1,279 @@
+// Package migrations versions the database schema with numbered SQL files.
+//
+// Each migration is a pair of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Every supported database has its own copy in
+// sql/postgres and sql/sqlite, which must describe the same schema. Applied
+// migrations are recorded in the schema_migrations table together with a
+// checksum of their up step, so edits to a migration after it ran are detected.
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var files embed.FS
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrDrift is returned when the database and the migrations of this build
+// do not agree
+var ErrDrift = errors.New("database schema drift")
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up step
+}
+
+// Record is a row of the schema_migrations table
+type Record struct {
+	Version   int       `gorm:"column:version;primarykey"`
+	Name      string    `gorm:"column:name;not null"`
+	Checksum  string    `gorm:"column:checksum;not null"`
+	AppliedAt time.Time `gorm:"column:applied_at;not null"`
+}
+
+// TableName sets the table name for Record
+func (Record) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes a migration and whether it was applied
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Modified  bool // applied with a different checksum than the file now has
+	Unknown   bool // applied, but missing from this build
+}
+
+// Migrator applies and rolls back migrations
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New creates a Migrator for the embedded migrations of the database's dialect
+func New(db *gorm.DB) (*Migrator, error) {
+	dir := "sql/" + db.Dialector.Name()
+	if _, err := fs.Stat(files, dir); err != nil {
+		return nil, fmt.Errorf("no migrations for database %s", db.Dialector.Name())
+	}
+
+	migrations, err := load(files, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load reads the migrations in dir, ordered by version
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			digest := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(digest[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down step", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// applied returns the recorded migrations by version
+func (m *Migrator) applied() (map[int]Record, error) {
+	if err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var records []Record
+	if err := m.db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	byVersion := make(map[int]Record, len(records))
+	for _, record := range records {
+		byVersion[record.Version] = record
+	}
+	return byVersion, nil
+}
+
+// Status lists every known or applied migration, ordered by version
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.Modified = record.Checksum != migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, record := range applied {
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, Status{
+			Version:   record.Version,
+			Name:      record.Name,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// Check returns ErrDrift if applied migrations were changed or are missing
+// from this build, or if allowPending is false and migrations are pending
+func (m *Migrator) Check(allowPending bool) error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, status := range statuses {
+		switch {
+		case status.Unknown:
+			problems = append(problems, fmt.Sprintf("migration %d (%s) was applied but is unknown to this build", status.Version, status.Name))
+		case status.Modified:
+			problems = append(problems, fmt.Sprintf("migration %d (%s) was changed after it was applied", status.Version, status.Name))
+		case status.AppliedAt == nil && !allowPending:
+			problems = append(problems, fmt.Sprintf("migration %d (%s) is pending", status.Version, status.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrDrift, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Up applies all pending migrations in order and returns them
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&Record{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to apply migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down rolls back the given number of most recently applied migrations and
+// returns them
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&Record{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to roll back migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
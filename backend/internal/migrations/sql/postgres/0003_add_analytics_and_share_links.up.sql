@@ -0,0 +1,43 @@
+-- CV access analytics, share links and settings
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id BIGSERIAL PRIMARY KEY,
+    token TEXT NOT NULL,
+    label TEXT NOT NULL DEFAULT '',
+    cv_file_id BIGINT,
+    language TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    max_downloads BIGINT NOT NULL DEFAULT 0,
+    download_count BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    last_accessed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_share_links_token ON share_links(token);
+CREATE INDEX IF NOT EXISTS idx_share_links_cv_file_id ON share_links(cv_file_id);
+
+CREATE TABLE IF NOT EXISTS cv_access_events (
+    id BIGSERIAL PRIMARY KEY,
+    cv_file_id BIGINT NOT NULL,
+    kind TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent_class TEXT NOT NULL DEFAULT '',
+    ip_hash TEXT NOT NULL DEFAULT '',
+    share_link_id BIGINT
+);
+
+ALTER TABLE cv_access_events ADD COLUMN IF NOT EXISTS share_link_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_cv_access_events_cv_file_id ON cv_access_events(cv_file_id);
+CREATE INDEX IF NOT EXISTS idx_cv_access_events_kind ON cv_access_events(kind);
+CREATE INDEX IF NOT EXISTS idx_cv_access_events_occurred_at ON cv_access_events(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_cv_access_events_share_link_id ON cv_access_events(share_link_id);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS achievements;
+DROP TABLE IF EXISTS hobbies;
+DROP TABLE IF EXISTS spoken_languages;
+DROP TABLE IF EXISTS industries;
+DROP TABLE IF EXISTS skill_categories;
+DROP TABLE IF EXISTS education;
+DROP TABLE IF EXISTS experiences;
@@ -0,0 +1,97 @@
+-- Structured CV content sections. Localized fields hold JSON objects keyed by
+-- language, list fields hold JSON arrays.
+
+CREATE TABLE IF NOT EXISTS experiences (
+    id BIGSERIAL PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    visible BOOLEAN NOT NULL,
+    title TEXT NOT NULL,
+    company TEXT NOT NULL,
+    industry TEXT,
+    period TEXT,
+    location TEXT,
+    description TEXT,
+    technologies TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_experiences_position ON experiences(position);
+
+CREATE TABLE IF NOT EXISTS education (
+    id BIGSERIAL PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    visible BOOLEAN NOT NULL,
+    degree TEXT,
+    institution TEXT,
+    period TEXT,
+    location TEXT,
+    description TEXT,
+    type TEXT NOT NULL DEFAULT 'formal',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_education_position ON education(position);
+
+CREATE TABLE IF NOT EXISTS skill_categories (
+    id BIGSERIAL PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    visible BOOLEAN NOT NULL,
+    category TEXT,
+    skills TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_skill_categories_position ON skill_categories(position);
+
+CREATE TABLE IF NOT EXISTS industries (
+    id BIGSERIAL PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    visible BOOLEAN NOT NULL,
+    name TEXT,
+    experience TEXT,
+    category TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_industries_position ON industries(position);
+
+CREATE TABLE IF NOT EXISTS spoken_languages (
+    id BIGSERIAL PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    visible BOOLEAN NOT NULL,
+    name TEXT,
+    level TEXT,
+    proficiency BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_spoken_languages_position ON spoken_languages(position);
+
+CREATE TABLE IF NOT EXISTS hobbies (
+    id BIGSERIAL PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    visible BOOLEAN NOT NULL,
+    name TEXT,
+    category TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hobbies_position ON hobbies(position);
+
+CREATE TABLE IF NOT EXISTS achievements (
+    id BIGSERIAL PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    visible BOOLEAN NOT NULL,
+    number TEXT NOT NULL,
+    text TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_achievements_position ON achievements(position);
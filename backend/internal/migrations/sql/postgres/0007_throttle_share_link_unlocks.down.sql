@@ -0,0 +1,3 @@
+ALTER TABLE share_links DROP COLUMN IF EXISTS unlock_blocked_until;
+ALTER TABLE share_links DROP COLUMN IF EXISTS last_failed_unlock_at;
+ALTER TABLE share_links DROP COLUMN IF EXISTS failed_unlock_count;
@@ -0,0 +1,5 @@
+-- Failed password attempts on share links, to slow down guessing
+
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS failed_unlock_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS last_failed_unlock_at TIMESTAMPTZ;
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS unlock_blocked_until TIMESTAMPTZ;
@@ -0,0 +1 @@
+ALTER TABLE cv_files ALTER COLUMN language SET DEFAULT 'en';
@@ -0,0 +1,4 @@
+-- The language of a CV version is always set on upload, from the request or
+-- CV_DEFAULT_LANGUAGE, so the column needs no default of its own
+
+ALTER TABLE cv_files ALTER COLUMN language DROP DEFAULT;
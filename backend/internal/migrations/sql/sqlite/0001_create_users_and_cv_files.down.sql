@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cv_files;
+DROP TABLE IF EXISTS users;
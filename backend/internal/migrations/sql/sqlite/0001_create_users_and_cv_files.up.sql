@@ -0,0 +1,36 @@
+-- Users and CV file metadata (file content lives in the blob store)
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    first_login BOOLEAN DEFAULT true,
+    login_count BIGINT DEFAULT 0,
+    last_password_change DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_login_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username);
+
+CREATE TABLE IF NOT EXISTS cv_files (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    filename TEXT NOT NULL,
+    original_name TEXT NOT NULL,
+    content_type TEXT NOT NULL DEFAULT 'application/pdf',
+    file_size BIGINT NOT NULL,
+    language TEXT NOT NULL DEFAULT 'en',
+    storage_key TEXT NOT NULL DEFAULT '',
+    content_sha256 TEXT NOT NULL DEFAULT '',
+    change_note TEXT NOT NULL DEFAULT '',
+    is_current BOOLEAN DEFAULT true,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_cv_files_language ON cv_files(language);
+CREATE INDEX IF NOT EXISTS idx_cv_files_is_current ON cv_files(is_current);
+CREATE INDEX IF NOT EXISTS idx_cv_files_created_at ON cv_files(created_at);
+CREATE INDEX IF NOT EXISTS idx_cv_files_deleted_at ON cv_files(deleted_at);
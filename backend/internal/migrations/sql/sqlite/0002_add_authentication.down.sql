@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS login_throttles;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS refresh_tokens;
+
+ALTER TABLE users DROP COLUMN locked_until;
+ALTER TABLE users DROP COLUMN last_failed_login_at;
+ALTER TABLE users DROP COLUMN failed_login_count;
+ALTER TABLE users DROP COLUMN totp_last_step;
+ALTER TABLE users DROP COLUMN totp_enabled;
+ALTER TABLE users DROP COLUMN totp_secret;
+ALTER TABLE users DROP COLUMN token_version;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS settings;
+DROP TABLE IF EXISTS cv_access_events;
+DROP TABLE IF EXISTS share_links;
@@ -0,0 +1,41 @@
+-- CV access analytics, share links and settings
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    token TEXT NOT NULL,
+    label TEXT NOT NULL DEFAULT '',
+    cv_file_id BIGINT,
+    language TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    max_downloads BIGINT NOT NULL DEFAULT 0,
+    download_count BIGINT NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    revoked_at DATETIME,
+    last_accessed_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_share_links_token ON share_links(token);
+CREATE INDEX IF NOT EXISTS idx_share_links_cv_file_id ON share_links(cv_file_id);
+
+CREATE TABLE IF NOT EXISTS cv_access_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    cv_file_id BIGINT NOT NULL,
+    kind TEXT NOT NULL,
+    occurred_at DATETIME NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent_class TEXT NOT NULL DEFAULT '',
+    ip_hash TEXT NOT NULL DEFAULT '',
+    share_link_id BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_cv_access_events_cv_file_id ON cv_access_events(cv_file_id);
+CREATE INDEX IF NOT EXISTS idx_cv_access_events_kind ON cv_access_events(kind);
+CREATE INDEX IF NOT EXISTS idx_cv_access_events_occurred_at ON cv_access_events(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_cv_access_events_share_link_id ON cv_access_events(share_link_id);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
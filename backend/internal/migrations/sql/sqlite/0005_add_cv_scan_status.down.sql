@@ -0,0 +1,2 @@
+ALTER TABLE cv_files DROP COLUMN scan_signature;
+ALTER TABLE cv_files DROP COLUMN scan_status;
@@ -0,0 +1,5 @@
+-- Malware scan status of CV files. Files uploaded before scanning existed
+-- count as unscanned.
+
+ALTER TABLE cv_files ADD COLUMN scan_status TEXT NOT NULL DEFAULT 'unscanned';
+ALTER TABLE cv_files ADD COLUMN scan_signature TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_cv_files_storage_key;
+DROP INDEX IF EXISTS idx_cv_files_content_sha256;
@@ -0,0 +1,4 @@
+-- Indexes for finding versions with the same content, which share a blob
+
+CREATE INDEX IF NOT EXISTS idx_cv_files_content_sha256 ON cv_files(content_sha256);
+CREATE INDEX IF NOT EXISTS idx_cv_files_storage_key ON cv_files(storage_key);
@@ -0,0 +1,3 @@
+ALTER TABLE share_links DROP COLUMN unlock_blocked_until;
+ALTER TABLE share_links DROP COLUMN last_failed_unlock_at;
+ALTER TABLE share_links DROP COLUMN failed_unlock_count;
@@ -0,0 +1,5 @@
+-- Failed password attempts on share links, to slow down guessing
+
+ALTER TABLE share_links ADD COLUMN failed_unlock_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE share_links ADD COLUMN last_failed_unlock_at DATETIME;
+ALTER TABLE share_links ADD COLUMN unlock_blocked_until DATETIME;
@@ -0,0 +1,33 @@
+CREATE TABLE cv_files_rebuilt (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    filename TEXT NOT NULL,
+    original_name TEXT NOT NULL,
+    content_type TEXT NOT NULL DEFAULT 'application/pdf',
+    file_size BIGINT NOT NULL,
+    language TEXT NOT NULL DEFAULT 'en',
+    storage_key TEXT NOT NULL DEFAULT '',
+    content_sha256 TEXT NOT NULL DEFAULT '',
+    change_note TEXT NOT NULL DEFAULT '',
+    is_current BOOLEAN DEFAULT true,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME,
+    scan_status TEXT NOT NULL DEFAULT 'unscanned',
+    scan_signature TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO cv_files_rebuilt (id, filename, original_name, content_type, file_size, language, storage_key,
+    content_sha256, change_note, is_current, created_at, updated_at, deleted_at, scan_status, scan_signature)
+SELECT id, filename, original_name, content_type, file_size, language, storage_key,
+    content_sha256, change_note, is_current, created_at, updated_at, deleted_at, scan_status, scan_signature
+FROM cv_files;
+
+DROP TABLE cv_files;
+ALTER TABLE cv_files_rebuilt RENAME TO cv_files;
+
+CREATE INDEX idx_cv_files_language ON cv_files(language);
+CREATE INDEX idx_cv_files_is_current ON cv_files(is_current);
+CREATE INDEX idx_cv_files_created_at ON cv_files(created_at);
+CREATE INDEX idx_cv_files_deleted_at ON cv_files(deleted_at);
+CREATE INDEX idx_cv_files_content_sha256 ON cv_files(content_sha256);
+CREATE INDEX idx_cv_files_storage_key ON cv_files(storage_key);
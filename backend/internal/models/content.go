@@ -0,0 +1,166 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LocalizedText holds one translation per language tag, e.g. {"en": "...", "de": "..."}
+type LocalizedText map[string]string
+
+// In returns the translation for language, falling back to fallback and then to any translation
+func (t LocalizedText) In(language, fallback string) string {
+	if text, ok := t[language]; ok && text != "" {
+		return text
+	}
+	if text, ok := t[fallback]; ok && text != "" {
+		return text
+	}
+	for _, text := range t {
+		if text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// Value stores the translations as a JSON object
+func (t LocalizedText) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(t)
+	return string(data), err
+}
+
+// Scan reads translations stored as a JSON object
+func (t *LocalizedText) Scan(value interface{}) error {
+	return scanJSON(value, t)
+}
+
+// StringList is a list of strings stored as a JSON array
+type StringList []string
+
+// Value stores the list as a JSON array
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+// Scan reads a list stored as a JSON array
+func (l *StringList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, dest)
+	}
+}
+
+// ContentEntry holds the fields shared by all structured CV content entries
+type ContentEntry struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Position  int       `gorm:"not null;default:0;index" json:"position"` // Sort order within the section
+	Visible   bool      `gorm:"not null" json:"visible"`                  // Hidden entries are only returned to admins
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// Entry gives access to the shared fields of any content model
+func (e *ContentEntry) Entry() *ContentEntry {
+	return e
+}
+
+// ContentItem is implemented by every content model through the embedded ContentEntry
+type ContentItem interface {
+	Entry() *ContentEntry
+}
+
+// Experience represents a position in the work experience section
+type Experience struct {
+	ContentEntry
+	Title        string        `gorm:"not null" json:"title"`
+	Company      string        `gorm:"not null" json:"company"`
+	Industry     string        `json:"industry"`
+	Period       LocalizedText `gorm:"type:text" json:"period"`
+	Location     LocalizedText `gorm:"type:text" json:"location"`
+	Description  LocalizedText `gorm:"type:text" json:"description"`
+	Technologies StringList    `gorm:"type:text" json:"technologies"`
+}
+
+// Education represents a degree or professional training
+type Education struct {
+	ContentEntry
+	Degree      LocalizedText `gorm:"type:text" json:"degree"`
+	Institution LocalizedText `gorm:"type:text" json:"institution"`
+	Period      string        `json:"period"`
+	Location    LocalizedText `gorm:"type:text" json:"location"`
+	Description LocalizedText `gorm:"type:text" json:"description"`
+	Type        string        `gorm:"not null;default:'formal'" json:"type"` // formal or professional
+}
+
+// TableName sets the table name for Education
+func (Education) TableName() string {
+	return "education"
+}
+
+// SkillCategory groups related skills
+type SkillCategory struct {
+	ContentEntry
+	Category LocalizedText `gorm:"type:text" json:"category"`
+	Skills   StringList    `gorm:"type:text" json:"skills"`
+}
+
+// Industry represents an industry the CV owner has worked in
+type Industry struct {
+	ContentEntry
+	Name       LocalizedText `gorm:"type:text" json:"name"`
+	Experience LocalizedText `gorm:"type:text" json:"experience"`
+	Category   LocalizedText `gorm:"type:text" json:"category"`
+}
+
+// SpokenLanguage represents a language the CV owner speaks
+type SpokenLanguage struct {
+	ContentEntry
+	Name        LocalizedText `gorm:"type:text" json:"name"`
+	Level       LocalizedText `gorm:"type:text" json:"level"`
+	Proficiency int           `gorm:"not null;default:0" json:"proficiency"` // 0-100
+}
+
+// Hobby represents an entry in the hobbies section
+type Hobby struct {
+	ContentEntry
+	Name     LocalizedText `gorm:"type:text" json:"name"`
+	Category LocalizedText `gorm:"type:text" json:"category"`
+}
+
+// Achievement represents a highlighted number such as "10+ years experience"
+type Achievement struct {
+	ContentEntry
+	Number string        `gorm:"not null" json:"number"`
+	Text   LocalizedText `gorm:"type:text" json:"text"`
+}
+
+// CVContent holds the entries of every content section
+type CVContent struct {
+	Experiences  []Experience     `json:"experiences"`
+	Education    []Education      `json:"education"`
+	Skills       []SkillCategory  `json:"skills"`
+	Industries   []Industry       `json:"industries"`
+	Languages    []SpokenLanguage `json:"languages"`
+	Hobbies      []Hobby          `json:"hobbies"`
+	Achievements []Achievement    `json:"achievements"`
+}
@@ -1,36 +1,295 @@
 package models
 
 import (
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // User represents a user in the database
 type User struct {
-	ID                 uint      `gorm:"primarykey" json:"id"`
-	Username           string    `gorm:"uniqueIndex;not null" json:"username"`
-	PasswordHash       string    `gorm:"not null" json:"password_hash"`
-	FirstLogin         bool      `gorm:"default:true" json:"first_login"`
-	LoginCount         int       `gorm:"default:0" json:"login_count"`
-	LastPasswordChange time.Time `gorm:"autoCreateTime" json:"last_password_change"`
-	LastLoginAt        time.Time `gorm:"autoCreateTime" json:"last_login_at"`
-	CreatedAt          time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt          time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID                 uint       `gorm:"primarykey" json:"id"`
+	Username           string     `gorm:"uniqueIndex;not null" json:"username"`
+	PasswordHash       string     `gorm:"not null" json:"-"`
+	Role               string     `gorm:"not null;default:'viewer'" json:"role"` // owner, editor or viewer
+	FirstLogin         bool       `gorm:"default:true" json:"first_login"`
+	LoginCount         int        `gorm:"default:0" json:"login_count"`
+	TokenVersion       int        `gorm:"not null;default:0" json:"-"`                     // Bumped to invalidate all issued access tokens
+	TOTPSecret         string     `gorm:"column:totp_secret;not null;default:''" json:"-"` // Base32 TOTP secret, set on enrollment
+	TOTPEnabled        bool       `gorm:"column:totp_enabled;not null;default:false" json:"totp_enabled"`
+	TOTPLastStep       int64      `gorm:"column:totp_last_step;not null;default:0" json:"-"` // Time step of the last accepted code, to prevent replays
+	FailedLoginCount   int        `gorm:"not null;default:0" json:"failed_login_count"`      // Consecutive failed logins, reset on success
+	LastFailedLoginAt  *time.Time `json:"last_failed_login_at"`
+	LockedUntil        *time.Time `json:"locked_until"` // Logins are refused until this time
+	LastPasswordChange time.Time  `gorm:"autoCreateTime" json:"last_password_change"`
+	LastLoginAt        time.Time  `gorm:"autoCreateTime" json:"last_login_at"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// LoginThrottle tracks failed logins from one client IP across all usernames
+type LoginThrottle struct {
+	IP            string     `gorm:"column:ip;primarykey" json:"ip"`
+	FailureCount  int        `gorm:"column:failure_count;not null;default:0" json:"failureCount"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at;not null" json:"lastFailureAt"`
+	BlockedUntil  *time.Time `gorm:"column:blocked_until" json:"blockedUntil"`
+}
+
+// TableName sets the table name for LoginThrottle
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
+
+// AuditEvent records a security-relevant event such as a lockout
+type AuditEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Action    string    `gorm:"column:action;not null;index" json:"action"`
+	Username  string    `gorm:"column:username;not null;default:''" json:"username"` // Account the event concerns, if any
+	IP        string    `gorm:"column:ip;not null;default:''" json:"ip"`             // Client IP the event concerns, if any
+	Actor     string    `gorm:"column:actor;not null;default:''" json:"actor"`       // Admin who triggered the event, empty for automatic events
+	Detail    string    `gorm:"column:detail;not null;default:''" json:"detail"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
+}
+
+// TableName sets the table name for AuditEvent
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the
+// authenticator is lost
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"column:user_id;not null;index" json:"userId"`
+	CodeHash  string     `gorm:"column:code_hash;not null;index" json:"-"` // SHA-256 of the normalized code
+	UsedAt    *time.Time `gorm:"column:used_at" json:"usedAt"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName sets the table name for RecoveryCode
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// User roles, from most to least privileged
+const (
+	RoleOwner  = "owner"  // everything, including user management and security settings
+	RoleEditor = "editor" // uploads and edits the CV, its content and share links
+	RoleViewer = "viewer" // read-only access to the admin area and statistics
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether the user's role grants at least the given role
+func (u *User) HasRole(role string) bool {
+	return roleRanks[u.Role] >= roleRanks[role] && roleRanks[role] > 0
+}
+
+// API key scopes. A key can only call the routes its scopes allow, and never
+// more than its owner's role permits.
+const (
+	ScopeCVRead       = "cv:read"       // CV info, versions and version downloads
+	ScopeCVUpload     = "cv:upload"     // uploading and publishing CVs
+	ScopeStatsRead    = "stats:read"    // statistics and analytics
+	ScopeContentRead  = "content:read"  // structured content, including hidden entries
+	ScopeContentWrite = "content:write" // importing structured content
+)
+
+// scopeRoles is the role a user needs to grant each scope to a key
+var scopeRoles = map[string]string{
+	ScopeCVRead:       RoleViewer,
+	ScopeCVUpload:     RoleEditor,
+	ScopeStatsRead:    RoleViewer,
+	ScopeContentRead:  RoleViewer,
+	ScopeContentWrite: RoleEditor,
+}
+
+// ScopeRole returns the role needed for a scope, or "" for unknown scopes
+func ScopeRole(scope string) string {
+	return scopeRoles[scope]
+}
+
+// APIKey lets scripts such as CI jobs call the API on behalf of a user
+// without logging in. Only the hash of the key is stored.
+type APIKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"column:user_id;not null;index" json:"userId"`
+	Name       string     `gorm:"column:name;not null" json:"name"`
+	Prefix     string     `gorm:"column:prefix;not null" json:"prefix"`          // First characters of the key, to recognise it
+	KeyHash    string     `gorm:"column:key_hash;not null;uniqueIndex" json:"-"` // SHA-256 of the key
+	Scopes     string     `gorm:"column:scopes;not null;default:''" json:"-"`    // Space-separated scopes
+	ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expiresAt"`            // nil never expires
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"lastUsedAt"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revokedAt"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName sets the table name for APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the scopes of the key
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope reports whether the key grants the given scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key can be used at the given time
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// RefreshToken is a long-lived token that can be exchanged once for a new
+// access and refresh token pair. Tokens from the same login share a family,
+// so reuse of an already rotated token revokes the whole session.
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"column:user_id;not null;index" json:"userId"`
+	TokenHash string     `gorm:"column:token_hash;not null;uniqueIndex" json:"-"` // SHA-256 of the token
+	FamilyID  string     `gorm:"column:family_id;not null;index" json:"familyId"`
+	UserAgent string     `gorm:"column:user_agent;not null;default:''" json:"userAgent"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null;index" json:"expiresAt"`
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revokedAt"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName sets the table name for RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
 }
 
 // CVFile represents a CV file stored in the database
 type CVFile struct {
-	ID           uint      `gorm:"primarykey" json:"id"`
-	FileName     string    `gorm:"column:filename;not null" json:"fileName"`
-	OriginalName string    `gorm:"column:original_name;not null" json:"originalName"`
-	ContentType  string    `gorm:"column:content_type;not null;default:'application/pdf'" json:"contentType"`
-	FileSize     int64     `gorm:"column:file_size;not null" json:"fileSize"`
-	FileData     []byte    `gorm:"column:file_data;not null" json:"-"` // Don't include in JSON
-	IsCurrent    bool      `gorm:"column:is_current;default:true;index" json:"isCurrent"`
-	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
-	UpdatedAt    time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+	ID            uint      `gorm:"primarykey" json:"id"`
+	FileName      string    `gorm:"column:filename;not null" json:"fileName"`
+	OriginalName  string    `gorm:"column:original_name;not null" json:"originalName"`
+	ContentType   string    `gorm:"column:content_type;not null;default:'application/pdf'" json:"contentType"`
+	FileSize      int64     `gorm:"column:file_size;not null" json:"fileSize"`
+	Language      string    `gorm:"column:language;not null;index" json:"language"`                // Language tag such as "en" or "de"
+	StorageKey    string    `gorm:"column:storage_key;not null;default:'';index" json:"-"`         // Blob store key of the file content, shared by versions with equal content
+	SHA256        string    `gorm:"column:content_sha256;not null;default:'';index" json:"sha256"` // Hex SHA-256 of the file content
+	ChangeNote    string    `gorm:"column:change_note;not null;default:''" json:"changeNote"`
+	ScanStatus    string    `gorm:"column:scan_status;not null;default:'unscanned'" json:"scanStatus"`        // clean, unscanned or quarantined
+	ScanSignature string    `gorm:"column:scan_signature;not null;default:''" json:"scanSignature,omitempty"` // malware found by the scanner
+	IsCurrent     bool      `gorm:"column:is_current;index" json:"isCurrent"`                                 // No GORM default, so that false is inserted as is
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+	// DeletedAt marks a soft-deleted version; it stays restorable until permanently deleted
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" json:"deletedAt"`
 }
 
 // TableName sets the table name for CVFile
 func (CVFile) TableName() string {
 	return "cv_files"
-}
\ No newline at end of file
+}
+
+// Malware scan statuses of a CV file
+const (
+	ScanStatusClean       = "clean"       // the scanner found nothing
+	ScanStatusUnscanned   = "unscanned"   // no scanner is configured, or it failed open
+	ScanStatusQuarantined = "quarantined" // the scanner found malware; never served or made current
+)
+
+// ScanResult is the outcome of scanning an uploaded CV file
+type ScanResult struct {
+	Status    string // one of the ScanStatus constants
+	Signature string // name of the malware, if quarantined
+}
+
+// Quarantined reports whether the scanner flagged the file
+func (f *CVFile) Quarantined() bool {
+	return f.ScanStatus == ScanStatusQuarantined
+}
+
+// CVAccessEvent records a single download or view of a CV version
+type CVAccessEvent struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	CVFileID       uint      `gorm:"column:cv_file_id;not null;index" json:"cvFileId"`
+	Kind           string    `gorm:"column:kind;not null;index" json:"kind"` // "download" or "view"
+	OccurredAt     time.Time `gorm:"column:occurred_at;not null;index" json:"occurredAt"`
+	Referrer       string    `gorm:"column:referrer;not null;default:''" json:"referrer"`               // Referring host, empty for direct access
+	UserAgentClass string    `gorm:"column:user_agent_class;not null;default:''" json:"userAgentClass"` // desktop, mobile, bot or other
+	IPHash         string    `gorm:"column:ip_hash;not null;default:''" json:"-"`                       // Salted hash of the client IP
+	ShareLinkID    *uint     `gorm:"column:share_link_id;index" json:"shareLinkId"`                     // Share link used for the access, nil for public access
+}
+
+// TableName sets the table name for CVAccessEvent
+func (CVAccessEvent) TableName() string {
+	return "cv_access_events"
+}
+
+// Share link statuses
+const (
+	ShareLinkActive    = "active"
+	ShareLinkExpired   = "expired"
+	ShareLinkRevoked   = "revoked"
+	ShareLinkExhausted = "exhausted"
+)
+
+// ShareLink grants access to the CV through a private URL
+type ShareLink struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	Token          string     `gorm:"column:token;not null;uniqueIndex" json:"token"`
+	Label          string     `gorm:"column:label;not null;default:''" json:"label"`               // Who the link was given to, e.g. "Acme recruiter"
+	CVFileID       *uint      `gorm:"column:cv_file_id;index" json:"versionId"`                    // Version to serve, nil to follow the current CV
+	Language       string     `gorm:"column:language;not null;default:''" json:"language"`         // Language of the current CV to serve, empty to negotiate
+	PasswordHash   string     `gorm:"column:password_hash;not null;default:''" json:"-"`           // bcrypt hash, empty if no password is required
+	MaxDownloads   int        `gorm:"column:max_downloads;not null;default:0" json:"maxDownloads"` // 0 means unlimited
+	DownloadCount  int        `gorm:"column:download_count;not null;default:0" json:"downloadCount"`
+	ExpiresAt      *time.Time `gorm:"column:expires_at" json:"expiresAt"`
+	RevokedAt      *time.Time `gorm:"column:revoked_at" json:"revokedAt"`
+	LastAccessedAt *time.Time `gorm:"column:last_accessed_at" json:"lastAccessedAt"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+
+	FailedUnlockCount  int        `gorm:"column:failed_unlock_count;not null;default:0" json:"-"` // Wrong passwords, reset on a correct one
+	LastFailedUnlockAt *time.Time `gorm:"column:last_failed_unlock_at" json:"-"`
+	UnlockBlockedUntil *time.Time `gorm:"column:unlock_blocked_until" json:"-"` // Passwords are refused until this time
+}
+
+// TableName sets the table name for ShareLink
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// Status reports whether the link can still be used at the given time
+func (l *ShareLink) Status(now time.Time) string {
+	switch {
+	case l.RevokedAt != nil:
+		return ShareLinkRevoked
+	case l.ExpiresAt != nil && !now.Before(*l.ExpiresAt):
+		return ShareLinkExpired
+	case l.MaxDownloads > 0 && l.DownloadCount >= l.MaxDownloads:
+		return ShareLinkExhausted
+	default:
+		return ShareLinkActive
+	}
+}
+
+// Setting is a key/value pair of runtime configuration changed by admins
+type Setting struct {
+	Key       string    `gorm:"column:key;primarykey" json:"key"`
+	Value     string    `gorm:"column:value;not null" json:"value"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName sets the table name for Setting
+func (Setting) TableName() string {
+	return "settings"
+}
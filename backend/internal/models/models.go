@@ -1,16 +1,57 @@
 package models
 
+import "time"
+
 // LoginRequest represents the login request payload
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
-// LoginResponse represents the login response
+// LoginResponse represents the login response. With two-factor
+// authentication enabled, the password step only returns a challenge token
+// that must be sent to /api/login/2fa together with a code.
 type LoginResponse struct {
-	Token      string `json:"token"`
-	Message    string `json:"message"`
-	FirstLogin bool   `json:"first_login,omitempty"`
+	Token             string `json:"token,omitempty"`
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	ExpiresIn         int    `json:"expires_in,omitempty"` // Access token lifetime in seconds
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+	Message           string `json:"message"`
+	FirstLogin        bool   `json:"first_login,omitempty"`
+}
+
+// LoginTwoFactorRequest represents the second login step payload
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+// TwoFactorCodeRequest represents a request confirmed with a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// EnrollTwoFactorRequest represents the request to create a TOTP secret
+type EnrollTwoFactorRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// ActivateTwoFactorRequest represents the request to turn on two-factor authentication
+type ActivateTwoFactorRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Code            string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the request to turn off two-factor authentication
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// RefreshRequest represents the token refresh and logout request payload
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // ChangePasswordRequest represents the password change request payload
@@ -19,13 +60,43 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" binding:"required"`
 }
 
-// ChangePasswordResponse represents the password change response
+// ChangePasswordResponse represents the password change response. It carries
+// a new token pair because the change revokes all existing tokens.
 type ChangePasswordResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error string `json:"error"`
-}
\ No newline at end of file
+}
+
+// ClearLockoutRequest selects the account and/or client IP to unlock
+type ClearLockoutRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+// CreateUserRequest represents the payload for adding a user
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// CreateAPIKeyRequest represents the payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// UpdateUserRequest changes the role of a user and/or resets their password
+type UpdateUserRequest struct {
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
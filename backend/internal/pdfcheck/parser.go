@@ -0,0 +1,506 @@
+package pdfcheck
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxDepth limits nesting of arrays and dictionaries
+const maxDepth = 64
+
+type name string
+
+type dict map[string]any
+
+type ref struct {
+	num, gen int64
+}
+
+type keyword string
+
+type stream struct {
+	dict dict
+	data []byte
+}
+
+// document is what the structural parse collects from a file
+type document struct {
+	objects     int
+	streams     []stream
+	trailers    []dict // trailer dictionaries and cross-reference stream dictionaries
+	names       map[string]bool
+	startxref   int
+	xrefOffsets map[int]bool // offsets of xref tables and object headers
+	inflated    int
+}
+
+// parser is a tokenizer over PDF syntax; it understands enough of the format
+// to walk the object structure and record every name it contains
+type parser struct {
+	data  []byte
+	pos   int
+	names map[string]bool
+}
+
+func parseDocument(data []byte) (*document, error) {
+	p := &parser{data: data, names: make(map[string]bool)}
+	doc := &document{
+		names:       p.names,
+		startxref:   -1,
+		xrefOffsets: make(map[int]bool),
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return doc, nil
+		}
+		start := p.pos
+
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case int64:
+			if err := p.parseObject(doc); err != nil {
+				return nil, err
+			}
+			doc.xrefOffsets[start] = true
+		case keyword:
+			switch tok {
+			case "xref":
+				if err := p.parseXref(doc); err != nil {
+					return nil, err
+				}
+				doc.xrefOffsets[start] = true
+			case "trailer":
+				if err := p.parseTrailer(doc); err != nil {
+					return nil, err
+				}
+			case "startxref":
+				offset, err := p.next()
+				n, ok := offset.(int64)
+				if err != nil || !ok || n < 0 || n >= int64(len(p.data)) {
+					return nil, fmt.Errorf("%w: invalid startxref offset", ErrCorrupt)
+				}
+				doc.startxref = p.skipSpaceFrom(int(n))
+			default:
+				return nil, p.unexpected(start, string(tok))
+			}
+		default:
+			return nil, p.unexpected(start, fmt.Sprint(tok))
+		}
+	}
+}
+
+// parseObject parses "gen obj <value> [stream ... endstream] endobj" after
+// the object number
+func (p *parser) parseObject(doc *document) error {
+	gen, err := p.next()
+	if _, ok := gen.(int64); err != nil || !ok {
+		return fmt.Errorf("%w: invalid object header at offset %d", ErrCorrupt, p.pos)
+	}
+	if tok, err := p.next(); err != nil || tok != keyword("obj") {
+		return fmt.Errorf("%w: invalid object header at offset %d", ErrCorrupt, p.pos)
+	}
+
+	value, err := p.parseValue(0)
+	if err != nil {
+		return err
+	}
+	doc.objects++
+
+	start := p.pos
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok == keyword("stream") {
+		d, ok := value.(dict)
+		if !ok {
+			return fmt.Errorf("%w: stream without dictionary at offset %d", ErrCorrupt, start)
+		}
+		s, err := p.parseStream(d)
+		if err != nil {
+			return err
+		}
+		doc.streams = append(doc.streams, s)
+		if d["Type"] == name("XRef") {
+			doc.trailers = append(doc.trailers, d)
+		}
+		start = p.pos
+		if tok, err = p.next(); err != nil {
+			return err
+		}
+	}
+	if tok != keyword("endobj") {
+		return fmt.Errorf("%w: missing endobj at offset %d", ErrCorrupt, start)
+	}
+	return nil
+}
+
+// parseStream reads stream data after the stream keyword, using /Length if
+// it is direct and correct and searching for endstream otherwise
+func (p *parser) parseStream(d dict) (stream, error) {
+	if bytes.HasPrefix(p.data[p.pos:], []byte("\r\n")) {
+		p.pos += 2
+	} else if p.pos < len(p.data) && (p.data[p.pos] == '\n' || p.data[p.pos] == '\r') {
+		p.pos++
+	}
+	start := p.pos
+
+	end := -1
+	if length, ok := d["Length"].(int64); ok && length >= 0 && length <= int64(len(p.data)-start) {
+		after := p.skipSpaceFrom(start + int(length))
+		if bytes.HasPrefix(p.data[after:], []byte("endstream")) {
+			end = start + int(length)
+		}
+	}
+	if end < 0 {
+		i := bytes.Index(p.data[start:], []byte("endstream"))
+		if i < 0 {
+			return stream{}, fmt.Errorf("%w: unterminated stream at offset %d", ErrTruncated, start)
+		}
+		end = start + i
+	}
+
+	p.pos = p.skipSpaceFrom(end) + len("endstream")
+	return stream{dict: d, data: p.data[start:end]}, nil
+}
+
+// parseXref skips a cross-reference table, checking its entries
+func (p *parser) parseXref(doc *document) error {
+	for {
+		start := p.pos
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok == keyword("trailer") {
+			return p.parseTrailer(doc)
+		}
+		first, ok := tok.(int64)
+		count, err := p.next()
+		n, ok2 := count.(int64)
+		if !ok || err != nil || !ok2 || first < 0 || n < 0 {
+			return fmt.Errorf("%w: invalid cross-reference table at offset %d", ErrCorrupt, start)
+		}
+		for i := int64(0); i < n; i++ {
+			offset, err1 := p.next()
+			gen, err2 := p.next()
+			kind, err3 := p.next()
+			_, ok1 := offset.(int64)
+			_, ok2 := gen.(int64)
+			if err1 != nil || err2 != nil || err3 != nil || !ok1 || !ok2 || (kind != keyword("n") && kind != keyword("f")) {
+				return fmt.Errorf("%w: invalid cross-reference entry at offset %d", ErrCorrupt, p.pos)
+			}
+		}
+	}
+}
+
+func (p *parser) parseTrailer(doc *document) error {
+	start := p.pos
+	value, err := p.parseValue(0)
+	if err != nil {
+		return err
+	}
+	d, ok := value.(dict)
+	if !ok {
+		return fmt.Errorf("%w: invalid trailer at offset %d", ErrCorrupt, start)
+	}
+	doc.trailers = append(doc.trailers, d)
+	return nil
+}
+
+// parseValue parses a direct object or an indirect reference
+func (p *parser) parseValue(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nesting too deep at offset %d", ErrCorrupt, p.pos)
+	}
+	start := p.pos
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case int64:
+		// "num gen R" is a reference; otherwise only the number is consumed
+		save := p.pos
+		if gen, err := p.next(); err == nil {
+			if g, ok := gen.(int64); ok {
+				if r, err := p.next(); err == nil && r == keyword("R") {
+					return ref{num: tok, gen: g}, nil
+				}
+			}
+		}
+		p.pos = save
+		return tok, nil
+	case keyword:
+		switch tok {
+		case "<<":
+			return p.parseDict(depth)
+		case "[":
+			return p.parseArray(depth)
+		case "true", "false", "null":
+			return tok, nil
+		}
+		return nil, p.unexpected(start, string(tok))
+	default:
+		return tok, nil
+	}
+}
+
+func (p *parser) parseDict(depth int) (dict, error) {
+	d := make(dict)
+	for {
+		p.skipSpace()
+		start := p.pos
+		if bytes.HasPrefix(p.data[p.pos:], []byte(">>")) {
+			p.pos += 2
+			return d, nil
+		}
+		key, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: dictionary key is not a name at offset %d", ErrCorrupt, start)
+		}
+		value, err := p.parseValue(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		d[string(k)] = value
+	}
+}
+
+func (p *parser) parseArray(depth int) ([]any, error) {
+	var values []any
+	for {
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return values, nil
+		}
+		value, err := p.parseValue(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+}
+
+// next returns the next token: an int64 or float64 number, a name, a string
+// ([]byte) or a keyword, which includes the "<<" and "[" delimiters
+func (p *parser) next() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("%w: unexpected end of file", ErrTruncated)
+	}
+
+	start := p.pos
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		p.pos++
+		raw := p.regular()
+		n, err := decodeName(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid name at offset %d", ErrCorrupt, start)
+		}
+		p.names[n] = true
+		return name(n), nil
+	case c == '(':
+		return p.literalString()
+	case c == '<' && bytes.HasPrefix(p.data[p.pos:], []byte("<<")):
+		p.pos += 2
+		return keyword("<<"), nil
+	case c == '<':
+		return p.hexString()
+	case c == '[':
+		p.pos++
+		return keyword("["), nil
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		return nil, p.unexpected(start, string(c))
+	}
+
+	word := p.regular()
+	if len(word) == 0 {
+		return nil, p.unexpected(start, string(p.data[start]))
+	}
+	if c := word[0]; c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		if n, err := strconv.ParseInt(string(word), 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(string(word), 64); err == nil {
+			return f, nil
+		}
+		return nil, p.unexpected(start, string(word))
+	}
+	return keyword(word), nil
+}
+
+// regular consumes a run of regular (non-delimiter, non-space) characters
+func (p *parser) regular() []byte {
+	start := p.pos
+	for p.pos < len(p.data) && !isSpace(p.data[p.pos]) && !isDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+func (p *parser) literalString() ([]byte, error) {
+	start := p.pos
+	p.pos++ // opening parenthesis
+	depth := 1
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case '\\':
+			p.pos++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return p.data[start:p.pos], nil
+			}
+		}
+		p.pos++
+	}
+	return nil, fmt.Errorf("%w: unterminated string at offset %d", ErrTruncated, start)
+}
+
+func (p *parser) hexString() ([]byte, error) {
+	start := p.pos
+	end := bytes.IndexByte(p.data[start:], '>')
+	if end < 0 {
+		return nil, fmt.Errorf("%w: unterminated hex string at offset %d", ErrTruncated, start)
+	}
+	for _, c := range p.data[start+1 : start+end] {
+		if !isSpace(c) && !isHexDigit(c) {
+			return nil, fmt.Errorf("%w: invalid hex string at offset %d", ErrCorrupt, start)
+		}
+	}
+	p.pos = start + end + 1
+	return p.data[start:p.pos], nil
+}
+
+func (p *parser) skipSpace() {
+	p.pos = p.skipSpaceFrom(p.pos)
+}
+
+// skipSpaceFrom returns the offset of the first token at or after pos,
+// skipping whitespace and comments
+func (p *parser) skipSpaceFrom(pos int) int {
+	for pos < len(p.data) {
+		switch c := p.data[pos]; {
+		case isSpace(c):
+			pos++
+		case c == '%':
+			for pos < len(p.data) && p.data[pos] != '\n' && p.data[pos] != '\r' {
+				pos++
+			}
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+func (p *parser) unexpected(offset int, token string) error {
+	if len(token) > 32 {
+		token = token[:32]
+	}
+	return fmt.Errorf("%w: unexpected %q at offset %d", ErrCorrupt, token, offset)
+}
+
+// scanObjectStreams inflates compressed object streams and records the names
+// of the objects inside them
+func (doc *document) scanObjectStreams() error {
+	for _, s := range doc.streams {
+		if s.dict["Type"] != name("ObjStm") {
+			continue
+		}
+		if filter, ok := s.dict["Filter"]; ok && filter != name("FlateDecode") {
+			if arr, ok := filter.([]any); !ok || len(arr) != 1 || arr[0] != name("FlateDecode") {
+				return fmt.Errorf("%w: unsupported object stream filter", ErrCorrupt)
+			}
+		}
+
+		data := s.data
+		if _, ok := s.dict["Filter"]; ok {
+			zr, err := zlib.NewReader(bytes.NewReader(s.data))
+			if err != nil {
+				return fmt.Errorf("%w: invalid object stream: %v", ErrCorrupt, err)
+			}
+			data, err = io.ReadAll(io.LimitReader(zr, int64(maxInflatedSize-doc.inflated)+1))
+			if err != nil {
+				return fmt.Errorf("%w: invalid object stream: %v", ErrCorrupt, err)
+			}
+			doc.inflated += len(data)
+			if doc.inflated > maxInflatedSize {
+				return fmt.Errorf("%w: object streams are too large", ErrCorrupt)
+			}
+		}
+
+		p := &parser{data: data, names: doc.names}
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.data) {
+				break
+			}
+			if _, err := p.parseValue(0); err != nil {
+				return fmt.Errorf("%w: invalid object stream: %v", ErrCorrupt, err)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeName resolves #xx escapes, which would otherwise hide names such as
+// /J#61vaScript from a plain text search
+func decodeName(raw []byte) (string, error) {
+	if bytes.IndexByte(raw, '#') < 0 {
+		return string(raw), nil
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '#' {
+			b.WriteByte(raw[i])
+			continue
+		}
+		if i+2 >= len(raw) {
+			return "", fmt.Errorf("incomplete escape")
+		}
+		decoded, err := hex.DecodeString(string(raw[i+1 : i+3]))
+		if err != nil {
+			return "", err
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
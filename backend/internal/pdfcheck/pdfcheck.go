@@ -0,0 +1,117 @@
+// Package pdfcheck validates uploaded PDFs by their content rather than by
+// file name or client-supplied content type.
+package pdfcheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// Error is a validation failure with a stable code for API clients
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Validation failures. Errors returned by Validate wrap one of these, so
+// they can be matched with errors.Is or unwrapped with errors.As.
+var (
+	ErrNotPDF       = &Error{Code: "not_pdf", Message: "file is not a PDF"}
+	ErrTruncated    = &Error{Code: "pdf_truncated", Message: "PDF is truncated"}
+	ErrCorrupt      = &Error{Code: "pdf_corrupt", Message: "PDF is corrupt"}
+	ErrEncrypted    = &Error{Code: "pdf_encrypted", Message: "encrypted or password-protected PDFs are not allowed"}
+	ErrJavaScript   = &Error{Code: "pdf_javascript", Message: "PDFs with embedded JavaScript are not allowed"}
+	ErrLaunchAction = &Error{Code: "pdf_launch_action", Message: "PDFs with launch actions are not allowed"}
+)
+
+// ContentType is the content type of files accepted by Validate
+const ContentType = "application/pdf"
+
+// eofWindow is how far from the end of the file the %%EOF marker may be;
+// some writers append whitespace or padding after it
+const eofWindow = 1024
+
+// maxInflatedSize bounds the decompressed size of all object streams, so that
+// a compression bomb cannot exhaust memory
+const maxInflatedSize = 64 << 20
+
+var headerPattern = regexp.MustCompile(`^%PDF-[12]\.[0-9]`)
+
+// Validate checks that data is a well-formed PDF without active content:
+//
+//   - the magic bytes identify it as a PDF
+//   - it ends with %%EOF and parses as a sequence of indirect objects,
+//     cross-reference sections and trailers
+//   - startxref points at a cross-reference section and a trailer names the
+//     document catalog
+//   - it is not encrypted
+//   - no object, including those in compressed object streams, is a
+//     JavaScript or launch action
+//
+// Object streams with filters other than FlateDecode cannot be inspected
+// and are rejected as corrupt.
+func Validate(data []byte) error {
+	if http.DetectContentType(data) != ContentType || !headerPattern.Match(data) {
+		return ErrNotPDF
+	}
+
+	tail := data[max(0, len(data)-eofWindow):]
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return fmt.Errorf("%w: no %%%%EOF marker at the end of the file", ErrTruncated)
+	}
+
+	doc, err := parseDocument(data)
+	if err != nil {
+		return err
+	}
+	if doc.objects == 0 {
+		return fmt.Errorf("%w: no objects", ErrCorrupt)
+	}
+	if doc.startxref < 0 {
+		return fmt.Errorf("%w: no startxref", ErrCorrupt)
+	}
+	if !doc.xrefOffsets[doc.startxref] {
+		return fmt.Errorf("%w: startxref does not point to a cross-reference section", ErrCorrupt)
+	}
+
+	hasRoot := false
+	for _, trailer := range doc.trailers {
+		if _, ok := trailer["Encrypt"]; ok {
+			return ErrEncrypted
+		}
+		if _, ok := trailer["Root"]; ok {
+			hasRoot = true
+		}
+	}
+	if !hasRoot {
+		return fmt.Errorf("%w: no document catalog", ErrCorrupt)
+	}
+
+	if err := doc.scanObjectStreams(); err != nil {
+		return err
+	}
+
+	if doc.names["JavaScript"] || doc.names["JS"] {
+		return ErrJavaScript
+	}
+	if doc.names["Launch"] {
+		return ErrLaunchAction
+	}
+	return nil
+}
+
+// Code returns the error code of a validation failure, or "" if err is not one
+func Code(err error) string {
+	var validationErr *Error
+	if errors.As(err, &validationErr) {
+		return validationErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package pdfcheck
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// buildPDF assembles a PDF from object bodies, numbered from 1, with a
+// correct cross-reference table and trailer
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+var basicObjects = []string{
+	"<< /Type /Catalog /Pages 2 0 R >>",
+	"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+	"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+}
+
+func withObjects(extra ...string) []byte {
+	return buildPDF(append(append([]string{}, basicObjects...), extra...)...)
+}
+
+// objectStream returns an object stream holding the given object bodies,
+// compressed with FlateDecode
+func objectStream(firstNum int, bodies ...string) string {
+	var header, content bytes.Buffer
+	for i, body := range bodies {
+		fmt.Fprintf(&header, "%d %d ", firstNum+i, content.Len())
+		content.WriteString(body + " ")
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(header.Bytes())
+	zw.Write(content.Bytes())
+	zw.Close()
+
+	return fmt.Sprintf("<< /Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+		len(bodies), header.Len(), compressed.Len(), compressed.Bytes())
+}
+
+func TestValidate(t *testing.T) {
+	valid := withObjects()
+
+	badStartxref := bytes.Replace(valid, []byte("startxref\n"), []byte("startxref\n1"), 1)
+
+	tests := []struct {
+		name string
+		data []byte
+		want *Error
+	}{
+		{"valid", valid, nil},
+		{"valid with clean object stream", withObjects(objectStream(10, "<< /Type /Annot /Subtype /Link >>")), nil},
+		{"not a PDF", []byte("hello, world"), ErrNotPDF},
+		{"truncated", valid[:len(valid)/2], ErrTruncated},
+		{"bad startxref", badStartxref, ErrCorrupt},
+		{"no catalog", bytes.Replace(valid, []byte("/Root 1 0 R"), []byte("/Info 1 0 R"), 1), ErrCorrupt},
+		{"encrypted", bytes.Replace(valid, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 4 0 R"), 1), ErrEncrypted},
+		{"JavaScript", withObjects("<< /S /JavaScript /JS (app.alert(1)) >>"), ErrJavaScript},
+		{"Launch", withObjects("<< /S /Launch /F (calc.exe) >>"), ErrLaunchAction},
+		{"JavaScript in object stream", withObjects(objectStream(10, "<< /S /JavaScript /JS (app.alert(1)) >>")), ErrJavaScript},
+		{"Launch in object stream", withObjects(objectStream(10, "<< /S /Launch /F (calc.exe) >>")), ErrLaunchAction},
+		{"hex-escaped JavaScript name", withObjects("<< /S /Java#53cript /JS (app.alert(1)) >>"), ErrJavaScript},
+		{"object stream with unsupported filter", withObjects("<< /Type /ObjStm /N 1 /First 4 /Filter /LZWDecode /Length 3 >>\nstream\nabc\nendstream"), ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.data)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Validate() = %v, want %v", err, tt.want)
+			}
+			if Code(err) != tt.want.Code {
+				t.Errorf("Code() = %q, want %q", Code(err), tt.want.Code)
+			}
+		})
+	}
+}
+
+func TestCode(t *testing.T) {
+	if got := Code(errors.New("other")); got != "" {
+		t.Errorf("Code() = %q, want empty", got)
+	}
+	if got := Code(fmt.Errorf("wrapped: %w", ErrJavaScript)); got != "pdf_javascript" {
+		t.Errorf("Code() = %q, want pdf_javascript", got)
+	}
+}
@@ -0,0 +1,169 @@
+package pdfgen
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Color is an RGB color with components between 0 and 1
+type Color struct {
+	R, G, B float64
+}
+
+// Document is a minimal PDF writer for text, lines and filled rectangles
+// using the standard fonts. Coordinates are in points from the bottom left.
+type Document struct {
+	title string
+	pages []*bytes.Buffer
+}
+
+// NewDocument creates an empty document with the given title
+func NewDocument(title string) *Document {
+	return &Document{title: title}
+}
+
+// AddPage starts a new page; subsequent drawing goes to it
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *Document) current() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text draws a single line of text with its baseline at (x, y)
+func (d *Document) Text(x, y float64, font Font, size float64, color Color, text string) {
+	d.drawEncoded(x, y, font, size, color, encode(text))
+}
+
+func (d *Document) drawEncoded(x, y float64, font Font, size float64, color Color, text []byte) {
+	fmt.Fprintf(d.current(), "BT %s rg /F%d %s Tf %s %s Td %s Tj ET\n",
+		formatColor(color), font+1, num(size), num(x), num(y), literal(text))
+}
+
+// Line draws a straight line
+func (d *Document) Line(x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(d.current(), "%s RG %s w %s %s m %s %s l S\n",
+		formatColor(color), num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// Rect draws a filled rectangle with its lower left corner at (x, y)
+func (d *Document) Rect(x, y, width, height float64, color Color) {
+	fmt.Fprintf(d.current(), "%s rg %s %s %s %s re f\n",
+		formatColor(color), num(x), num(y), num(width), num(height))
+}
+
+// Bytes serializes the document. The output contains no timestamps, so the
+// same content always produces identical bytes.
+func (d *Document) Bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+
+	// Object numbers: 1 catalog, 2 page tree, 3 info, 4-6 fonts, then a page
+	// object and a content stream per page
+	startObject := func() int {
+		offsets = append(offsets, out.Len())
+		id := len(offsets)
+		fmt.Fprintf(&out, "%d 0 obj\n", id)
+		return id
+	}
+	const firstPage = 4 + len(baseFonts)
+
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	startObject()
+	out.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	startObject()
+	out.WriteString("<< /Type /Pages /Kids [")
+	for i := range d.pages {
+		fmt.Fprintf(&out, " %d 0 R", firstPage+2*i)
+	}
+	fmt.Fprintf(&out, " ] /Count %d >>\nendobj\n", len(d.pages))
+
+	startObject()
+	fmt.Fprintf(&out, "<< /Title %s /Producer (cv-backend) >>\nendobj\n", literal(encode(d.title)))
+
+	for _, name := range baseFonts {
+		startObject()
+		fmt.Fprintf(&out, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\nendobj\n", name)
+	}
+
+	fontResources := ""
+	for i := range baseFonts {
+		fontResources += fmt.Sprintf(" /F%d %d 0 R", i+1, 4+i)
+	}
+
+	for _, page := range d.pages {
+		pageID := startObject()
+		fmt.Fprintf(&out, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font <<%s >> >> /Contents %d 0 R >>\nendobj\n",
+			num(PageWidth), num(PageHeight), fontResources, pageID+1)
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return nil, fmt.Errorf("failed to compress page: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress page: %w", err)
+		}
+
+		startObject()
+		fmt.Fprintf(&out, "<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+		out.Write(compressed.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes(), nil
+}
+
+// literal encodes bytes as a PDF literal string, escaping non-ASCII bytes
+func literal(text []byte) string {
+	var b bytes.Buffer
+	b.WriteByte('(')
+	for _, c := range text {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 32 || c > 126:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func formatColor(c Color) string {
+	return num(c.R) + " " + num(c.G) + " " + num(c.B)
+}
+
+// num formats a coordinate with at most two decimals
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
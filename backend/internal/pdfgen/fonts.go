@@ -0,0 +1,144 @@
+package pdfgen
+
+import (
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Font identifies one of the standard Type 1 fonts every PDF viewer provides
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+	Italic
+)
+
+// baseFonts are the PostScript names of the fonts, indexed by Font
+var baseFonts = [...]string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique"}
+
+// Glyph widths for WinAnsi codes 32-126 in 1/1000 em, from the Adobe core font metrics.
+// Helvetica-Oblique shares the metrics of Helvetica.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// Widths of the WinAnsi punctuation above 127 that CV texts commonly use
+var punctuationWidths = map[byte]int{
+	0x85: 1000, // ellipsis
+	0x91: 222,  // left single quote
+	0x92: 222,  // right single quote
+	0x93: 333,  // left double quote
+	0x94: 333,  // right double quote
+	0x95: 350,  // bullet
+	0x96: 556,  // en dash
+	0x97: 1000, // em dash
+	0xA0: 278,  // no-break space
+}
+
+// serbianCyrillic maps Serbian Cyrillic to its official Latin transliteration,
+// since the standard fonts only cover Western European characters
+var serbianCyrillic = map[rune]string{
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Ђ': "Đ", 'Е': "E", 'Ж': "Ž",
+	'З': "Z", 'И': "I", 'Ј': "J", 'К': "K", 'Л': "L", 'Љ': "Lj", 'М': "M", 'Н': "N",
+	'Њ': "Nj", 'О': "O", 'П': "P", 'Р': "R", 'С': "S", 'Т': "T", 'Ћ': "Ć", 'У': "U",
+	'Ф': "F", 'Х': "H", 'Ц': "C", 'Ч': "Č", 'Џ': "Dž", 'Ш': "Š",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'ђ': "đ", 'е': "e", 'ж': "ž",
+	'з': "z", 'и': "i", 'ј': "j", 'к': "k", 'л': "l", 'љ': "lj", 'м': "m", 'н': "n",
+	'њ': "nj", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'ћ': "ć", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "c", 'ч': "č", 'џ': "dž", 'ш': "š",
+}
+
+// latinFallbacks replaces letters missing from WinAnsi with their base letter
+var latinFallbacks = map[rune]string{
+	'Č': "C", 'č': "c", 'Ć': "C", 'ć': "c", 'Đ': "Dj", 'đ': "dj",
+	'Ł': "L", 'ł': "l", 'Ő': "O", 'ő': "o", 'Ű': "U", 'ű': "u",
+}
+
+// encode converts text to WinAnsi bytes, transliterating or replacing
+// characters the standard fonts cannot show
+func encode(text string) []byte {
+	var expanded strings.Builder
+	for _, r := range text {
+		if latin, ok := serbianCyrillic[r]; ok {
+			expanded.WriteString(latin)
+		} else {
+			expanded.WriteRune(r)
+		}
+	}
+
+	out := make([]byte, 0, expanded.Len())
+	for _, r := range expanded.String() {
+		if r == '\n' || r == '\t' {
+			out = append(out, ' ')
+			continue
+		}
+		if b, ok := charmap.Windows1252.EncodeRune(r); ok && b >= 32 {
+			out = append(out, b)
+			continue
+		}
+		if fallback, ok := latinFallbacks[r]; ok {
+			out = append(out, fallback...)
+			continue
+		}
+		out = append(out, '?')
+	}
+	return out
+}
+
+// glyphWidth returns the width of a WinAnsi character in 1/1000 em
+func glyphWidth(font Font, b byte) int {
+	widths := &helveticaWidths
+	if font == Bold {
+		widths = &helveticaBoldWidths
+	}
+
+	if b >= 32 && b <= 126 {
+		return widths[b-32]
+	}
+	if w, ok := punctuationWidths[b]; ok {
+		return w
+	}
+
+	// Accented letters are as wide as their base letter
+	if r := charmap.Windows1252.DecodeByte(b); r != 0 {
+		if base := baseLetter(r); base != 0 {
+			return widths[base-32]
+		}
+	}
+	return 556
+}
+
+// baseLetter maps common accented Latin letters to their ASCII base letter
+func baseLetter(r rune) byte {
+	const accented = "ÀÁÂÃÄÅàáâãäåÇçÈÉÊËèéêëÌÍÎÏìíîïÑñÒÓÔÕÖØòóôõöøÙÚÛÜùúûüÝýÿŠšŽž"
+	const base = "AAAAAAaaaaaaCcEEEEeeeeIIIIiiiiNnOOOOOOooooooUUUUuuuuYyySsZz"
+	if i := strings.IndexRune(accented, r); i >= 0 {
+		return base[len([]rune(accented[:i]))]
+	}
+	return 0
+}
+
+// textWidth returns the width of encoded text in points
+func textWidth(font Font, size float64, text []byte) float64 {
+	total := 0
+	for _, b := range text {
+		total += glyphWidth(font, b)
+	}
+	return float64(total) * size / 1000
+}
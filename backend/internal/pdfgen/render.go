@@ -0,0 +1,341 @@
+package pdfgen
+
+import (
+	"bytes"
+	"cv-backend/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template controls the look of a generated CV
+type Template struct {
+	Name        string
+	Accent      Color   // color of headings and rules
+	Text        Color   // body text color
+	Muted       Color   // color of secondary lines such as periods and locations
+	BodySize    float64 // body font size in points
+	Margin      float64
+	HeaderBand  bool // draw the name on a filled accent band
+	RuledTitles bool // underline section titles
+}
+
+var templates = map[string]Template{
+	"classic": {
+		Name:        "classic",
+		Accent:      Color{0.1, 0.1, 0.1},
+		Text:        Color{0.1, 0.1, 0.1},
+		Muted:       Color{0.4, 0.4, 0.4},
+		BodySize:    10,
+		Margin:      56,
+		RuledTitles: true,
+	},
+	"modern": {
+		Name:       "modern",
+		Accent:     Color{0.15, 0.39, 0.92},
+		Text:       Color{0.12, 0.16, 0.22},
+		Muted:      Color{0.42, 0.45, 0.5},
+		BodySize:   10,
+		Margin:     50,
+		HeaderBand: true,
+	},
+	"compact": {
+		Name:        "compact",
+		Accent:      Color{0.2, 0.2, 0.2},
+		Text:        Color{0.1, 0.1, 0.1},
+		Muted:       Color{0.45, 0.45, 0.45},
+		BodySize:    8.5,
+		Margin:      36,
+		RuledTitles: true,
+	},
+}
+
+// DefaultTemplate is used when no template is requested
+const DefaultTemplate = "classic"
+
+// Templates returns the names of the available templates
+func Templates() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupTemplate returns the template with the given name
+func LookupTemplate(name string) (Template, bool) {
+	if name == "" {
+		name = DefaultTemplate
+	}
+	tpl, ok := templates[name]
+	return tpl, ok
+}
+
+// Options describe what to render
+type Options struct {
+	Template        Template
+	Language        string // language of the rendered translations
+	DefaultLanguage string // used where a translation is missing
+	Name            string // CV owner's name shown in the header
+	Headline        string // optional line below the name
+}
+
+// sectionTitles are the headings per language; missing languages use English
+var sectionTitles = map[string]map[string]string{
+	"en": {"experience": "Experience", "education": "Education", "skills": "Skills", "languages": "Languages", "hobbies": "Hobbies"},
+	"sr": {"experience": "Iskustvo", "education": "Obrazovanje", "skills": "Veštine", "languages": "Jezici", "hobbies": "Hobiji"},
+	"de": {"experience": "Berufserfahrung", "education": "Ausbildung", "skills": "Kenntnisse", "languages": "Sprachen", "hobbies": "Hobbys"},
+}
+
+// Render lays out the CV content as a PDF
+func Render(content *models.CVContent, opts Options) ([]byte, error) {
+	w := &layout{
+		doc:  NewDocument(opts.Name + " CV"),
+		tpl:  opts.Template,
+		lang: opts.Language,
+		def:  opts.DefaultLanguage,
+	}
+	w.newPage()
+
+	w.header(opts.Name, opts.Headline, content.Achievements)
+
+	if len(content.Experiences) > 0 {
+		w.sectionTitle(w.title("experience"))
+		for _, exp := range content.Experiences {
+			w.entryHeader(exp.Title, w.text(exp.Period))
+			w.paragraph(strings.Join(nonEmpty(exp.Company, exp.Industry, w.text(exp.Location)), " • "), Regular, w.tpl.BodySize, w.tpl.Muted)
+			w.paragraph(w.text(exp.Description), Regular, w.tpl.BodySize, w.tpl.Text)
+			if len(exp.Technologies) > 0 {
+				w.paragraph(strings.Join(exp.Technologies, ", "), Italic, w.tpl.BodySize-1, w.tpl.Muted)
+			}
+			w.gap(w.tpl.BodySize * 0.8)
+		}
+	}
+
+	if len(content.Education) > 0 {
+		w.sectionTitle(w.title("education"))
+		for _, edu := range content.Education {
+			w.entryHeader(w.text(edu.Degree), edu.Period)
+			w.paragraph(strings.Join(nonEmpty(w.text(edu.Institution), w.text(edu.Location)), " • "), Regular, w.tpl.BodySize, w.tpl.Muted)
+			w.paragraph(w.text(edu.Description), Regular, w.tpl.BodySize, w.tpl.Text)
+			w.gap(w.tpl.BodySize * 0.8)
+		}
+	}
+
+	if len(content.Skills) > 0 {
+		w.sectionTitle(w.title("skills"))
+		for _, category := range content.Skills {
+			w.labeledParagraph(w.text(category.Category), strings.Join(category.Skills, ", "))
+		}
+		w.gap(w.tpl.BodySize * 0.8)
+	}
+
+	if len(content.Languages) > 0 {
+		w.sectionTitle(w.title("languages"))
+		for _, language := range content.Languages {
+			w.labeledParagraph(w.text(language.Name), w.text(language.Level))
+		}
+		w.gap(w.tpl.BodySize * 0.8)
+	}
+
+	if len(content.Hobbies) > 0 {
+		w.sectionTitle(w.title("hobbies"))
+		hobbies := make([]string, 0, len(content.Hobbies))
+		for _, hobby := range content.Hobbies {
+			hobbies = append(hobbies, w.text(hobby.Name))
+		}
+		w.paragraph(strings.Join(hobbies, ", "), Regular, w.tpl.BodySize, w.tpl.Text)
+	}
+
+	return w.doc.Bytes()
+}
+
+// layout flows content top to bottom, starting new pages as needed
+type layout struct {
+	doc  *Document
+	tpl  Template
+	lang string
+	def  string
+	y    float64
+}
+
+func (w *layout) text(t models.LocalizedText) string {
+	return strings.TrimSpace(t.In(w.lang, w.def))
+}
+
+func (w *layout) title(key string) string {
+	if titles, ok := sectionTitles[w.lang]; ok {
+		return titles[key]
+	}
+	return sectionTitles["en"][key]
+}
+
+func (w *layout) width() float64 {
+	return PageWidth - 2*w.tpl.Margin
+}
+
+func (w *layout) newPage() {
+	w.doc.AddPage()
+	w.y = PageHeight - w.tpl.Margin
+}
+
+// ensure starts a new page if less than height points are left
+func (w *layout) ensure(height float64) {
+	if w.y-height < w.tpl.Margin {
+		w.newPage()
+	}
+}
+
+func (w *layout) gap(height float64) {
+	w.y -= height
+}
+
+func (w *layout) header(name, headline string, achievements []models.Achievement) {
+	size := w.tpl.BodySize * 2.2
+	if w.tpl.HeaderBand {
+		bandHeight := size * 2.2
+		if headline != "" {
+			bandHeight += w.tpl.BodySize * 1.8
+		}
+		w.doc.Rect(0, PageHeight-w.tpl.Margin/2-bandHeight, PageWidth, bandHeight+w.tpl.Margin/2, w.tpl.Accent)
+		w.y = PageHeight - w.tpl.Margin/2 - size*1.4
+		w.doc.Text(w.tpl.Margin, w.y, Bold, size, Color{1, 1, 1}, name)
+		if headline != "" {
+			w.y -= w.tpl.BodySize * 1.8
+			w.doc.Text(w.tpl.Margin, w.y, Regular, w.tpl.BodySize*1.2, Color{1, 1, 1}, headline)
+		}
+		w.y = PageHeight - w.tpl.Margin/2 - bandHeight - w.tpl.BodySize*2
+	} else {
+		w.y -= size
+		w.doc.Text(w.tpl.Margin, w.y, Bold, size, w.tpl.Accent, name)
+		if headline != "" {
+			w.y -= w.tpl.BodySize * 1.8
+			w.doc.Text(w.tpl.Margin, w.y, Regular, w.tpl.BodySize*1.2, w.tpl.Muted, headline)
+		}
+		w.y -= w.tpl.BodySize
+		w.doc.Line(w.tpl.Margin, w.y, PageWidth-w.tpl.Margin, w.y, 1, w.tpl.Accent)
+		w.y -= w.tpl.BodySize * 1.5
+	}
+
+	if len(achievements) > 0 {
+		highlights := make([]string, 0, len(achievements))
+		for _, achievement := range achievements {
+			highlights = append(highlights, strings.TrimSpace(achievement.Number+" "+w.text(achievement.Text)))
+		}
+		w.paragraph(strings.Join(highlights, "  •  "), Bold, w.tpl.BodySize, w.tpl.Text)
+		w.gap(w.tpl.BodySize)
+	}
+}
+
+func (w *layout) sectionTitle(title string) {
+	size := w.tpl.BodySize * 1.4
+	// Keep the title together with the first lines of the section
+	w.ensure(size*2 + w.tpl.BodySize*4)
+	w.y -= size
+	w.doc.Text(w.tpl.Margin, w.y, Bold, size, w.tpl.Accent, strings.ToUpper(title))
+	if w.tpl.RuledTitles {
+		w.y -= size * 0.4
+		w.doc.Line(w.tpl.Margin, w.y, PageWidth-w.tpl.Margin, w.y, 0.5, w.tpl.Muted)
+	}
+	w.y -= size * 0.8
+}
+
+// entryHeader draws a bold title with a right-aligned period
+func (w *layout) entryHeader(title, period string) {
+	size := w.tpl.BodySize * 1.15
+	w.ensure(size * 1.4 * 3)
+	w.y -= size * 1.2
+
+	encodedPeriod := encode(period)
+	periodWidth := textWidth(Regular, w.tpl.BodySize, encodedPeriod)
+	if len(encodedPeriod) > 0 {
+		w.doc.drawEncoded(PageWidth-w.tpl.Margin-periodWidth, w.y, Regular, w.tpl.BodySize, w.tpl.Muted, encodedPeriod)
+	}
+
+	titleLines := wrap(encode(title), Bold, size, w.width()-periodWidth-10)
+	for i, line := range titleLines {
+		if i > 0 {
+			w.y -= size * 1.25
+		}
+		w.doc.drawEncoded(w.tpl.Margin, w.y, Bold, size, w.tpl.Text, line)
+	}
+}
+
+// paragraph draws word-wrapped text
+func (w *layout) paragraph(text string, font Font, size float64, color Color) {
+	if text == "" {
+		return
+	}
+	for _, line := range wrap(encode(text), font, size, w.width()) {
+		w.ensure(size * 1.4)
+		w.y -= size * 1.4
+		w.doc.drawEncoded(w.tpl.Margin, w.y, font, size, color, line)
+	}
+}
+
+// labeledParagraph draws "Label: text" with a bold label
+func (w *layout) labeledParagraph(label, text string) {
+	size := w.tpl.BodySize
+	prefix := encode(label + ": ")
+	prefixWidth := textWidth(Bold, size, prefix)
+
+	lines := wrap(encode(text), Regular, size, w.width()-prefixWidth)
+	if len(lines) == 0 {
+		lines = [][]byte{nil}
+	}
+	for i, line := range lines {
+		w.ensure(size * 1.4)
+		w.y -= size * 1.4
+		if i == 0 {
+			w.doc.drawEncoded(w.tpl.Margin, w.y, Bold, size, w.tpl.Text, prefix)
+		}
+		w.doc.drawEncoded(w.tpl.Margin+prefixWidth, w.y, Regular, size, w.tpl.Text, line)
+	}
+}
+
+// wrap splits encoded text into lines no wider than maxWidth; words longer
+// than a line are kept whole
+func wrap(text []byte, font Font, size, maxWidth float64) [][]byte {
+	var lines [][]byte
+	var current []byte
+	spaceWidth := textWidth(font, size, []byte(" "))
+	currentWidth := 0.0
+
+	for _, word := range bytes.Fields(text) {
+		wordWidth := textWidth(font, size, word)
+		if len(current) > 0 && currentWidth+spaceWidth+wordWidth > maxWidth {
+			lines = append(lines, current)
+			current, currentWidth = nil, 0
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+			currentWidth += spaceWidth
+		}
+		current = append(current, word...)
+		currentWidth += wordWidth
+	}
+	if len(current) > 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			out = append(out, strings.TrimSpace(v))
+		}
+	}
+	return out
+}
+
+// Filename returns a download name for a generated CV
+func Filename(name, language string) string {
+	base := strings.Join(strings.Fields(string(encode(name))), "_")
+	if base == "" {
+		base = "CV"
+	}
+	return fmt.Sprintf("%s_CV_%s.pdf", base, language)
+}
@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the chunks streamed to clamd; it must stay
+// below clamd's StreamMaxLength
+const clamdChunkSize = 64 << 10
+
+// Clamd scans files with a ClamAV daemon, streaming them over its INSTREAM
+// command. Every scan uses its own connection.
+type Clamd struct {
+	network string // tcp or unix
+	address string
+	timeout time.Duration
+}
+
+// NewClamd creates a scanner for the clamd listening at address. Each scan,
+// including connecting, is limited to timeout.
+func NewClamd(network, address string, timeout time.Duration) *Clamd {
+	return &Clamd{network: network, address: address, timeout: timeout}
+}
+
+// Scan streams r to clamd and returns its verdict
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return Result{}, fmt.Errorf("failed to send INSTREAM to clamd: %w", err)
+	}
+	if err := c.stream(conn, r); err != nil {
+		// clamd closes the connection when the stream exceeds its limit; its
+		// reply explains why
+		if reply, replyErr := readReply(conn); replyErr == nil && strings.HasSuffix(reply, "ERROR") {
+			return Result{}, fmt.Errorf("clamd failed to scan: %s", reply)
+		}
+		return Result{}, err
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return Result{}, err
+	}
+	return parseReply(reply)
+}
+
+// stream sends r as length-prefixed chunks, ended by an empty chunk
+func (c *Clamd) stream(conn net.Conn, r io.Reader) error {
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return fmt.Errorf("failed to stream file to clamd: %w", werr)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to stream file to clamd: %w", err)
+	}
+	return nil
+}
+
+// dial connects to clamd with a deadline of the timeout or ctx, whichever
+// comes first. Cancelling ctx aborts the exchange.
+func (c *Clamd) dial(ctx context.Context) (net.Conn, error) {
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set clamd deadline: %w", err)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	return &clamdConn{Conn: conn, stop: stop}, nil
+}
+
+// clamdConn stops watching the context when it is closed
+type clamdConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (c *clamdConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
+// readReply reads a reply terminated by a NUL byte, as requested by the z
+// prefix of the commands
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
+
+// parseReply interprets an INSTREAM reply such as "stream: OK" or
+// "stream: Eicar-Test-Signature FOUND"
+func parseReply(reply string) (Result, error) {
+	verdict := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case verdict == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.HasSuffix(verdict, "ERROR"):
+		return Result{}, fmt.Errorf("clamd failed to scan: %s", reply)
+	}
+	return Result{}, fmt.Errorf("unexpected clamd reply %q", reply)
+}
@@ -0,0 +1,162 @@
+// Package clamdtest provides a fake clamd for tests, in the manner of
+// net/http/httptest.
+package clamdtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// EICAR is the standard antivirus test file. Servers report content
+// containing it as Eicar-Test-Signature.
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// maxStreamSize mirrors clamd's default StreamMaxLength of 25MB
+const maxStreamSize = 25 << 20
+
+// Server is a fake clamd on a local TCP port. It answers PING and INSTREAM
+// and reports streams that contain one of its signatures as infected.
+type Server struct {
+	// Addr is the server address, e.g. tcp://127.0.0.1:49152, usable as
+	// CLAMD_ADDRESS
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu         sync.Mutex
+	signatures map[string]string // content pattern -> signature name
+	failing    bool
+	scans      int
+}
+
+// NewServer starts a fake clamd that knows the EICAR signature. The caller
+// must Close it. It panics if it cannot listen, like httptest.NewServer.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("clamdtest: failed to listen: %v", err))
+	}
+
+	s := &Server{
+		Addr:       "tcp://" + listener.Addr().String(),
+		listener:   listener,
+		signatures: map[string]string{EICAR: "Eicar-Test-Signature"},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// AddSignature reports content containing pattern as infected with name
+func (s *Server) AddSignature(pattern, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signatures[pattern] = name
+}
+
+// SetFailing makes scans fail with an ERROR reply, like a clamd whose
+// signature database failed to load
+func (s *Server) SetFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+// Scans returns the number of streams scanned so far
+func (s *Server) Scans() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scans
+}
+
+// Close stops the server and waits for open connections to finish
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle answers a single z-prefixed command, as clamd does without IDSESSION
+func (s *Server) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	command, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch strings.TrimSuffix(command, "\x00") {
+	case "zPING":
+		reply(conn, "PONG")
+	case "zINSTREAM":
+		content, err := readStream(r)
+		if err != nil {
+			reply(conn, "INSTREAM size limit exceeded. ERROR")
+			return
+		}
+		reply(conn, "stream: "+s.verdict(content))
+	default:
+		reply(conn, "UNKNOWN COMMAND")
+	}
+}
+
+// readStream reads length-prefixed chunks up to the empty chunk
+func readStream(r io.Reader) ([]byte, error) {
+	var content bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return content.Bytes(), nil
+		}
+		if content.Len()+int(size) > maxStreamSize {
+			return nil, fmt.Errorf("stream too large")
+		}
+		if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Server) verdict(content []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scans++
+	if s.failing {
+		return "Can't allocate memory ERROR"
+	}
+	for pattern, name := range s.signatures {
+		if bytes.Contains(content, []byte(pattern)) {
+			return name + " FOUND"
+		}
+	}
+	return "OK"
+}
+
+func reply(conn net.Conn, message string) {
+	io.WriteString(conn, message+"\x00")
+}
@@ -0,0 +1,53 @@
+// Package scanner checks uploaded files for malware before they are stored.
+package scanner
+
+import (
+	"context"
+	"cv-backend/internal/config"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Result is the verdict on a scanned file
+type Result struct {
+	Infected  bool
+	Signature string // name of the malware, if infected
+}
+
+// Scanner scans file content for malware. An error means the content could
+// not be scanned, not that it is infected.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
+
+// New creates the scanner configured by cfg (CLAMD_ADDRESS). It returns nil
+// if scanning is disabled.
+func New(cfg config.ScanConfig) (Scanner, error) {
+	if cfg.ClamdAddress == "" {
+		return nil, nil
+	}
+
+	network, address, err := ParseClamdAddress(cfg.ClamdAddress)
+	if err != nil {
+		return nil, err
+	}
+	return NewClamd(network, address, cfg.Timeout), nil
+}
+
+// ParseClamdAddress splits a clamd address such as tcp://clamav:3310 or
+// unix:///run/clamav/clamd.sock into a network and an address for net.Dial
+func ParseClamdAddress(s string) (network, address string, err error) {
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid clamd address %q: %w", s, err)
+	}
+
+	switch {
+	case parsed.Scheme == "tcp" && parsed.Host != "" && parsed.Port() != "":
+		return "tcp", parsed.Host, nil
+	case parsed.Scheme == "unix" && parsed.Path != "":
+		return "unix", parsed.Path, nil
+	}
+	return "", "", fmt.Errorf("invalid clamd address %q (expected tcp://host:port or unix:///path)", s)
+}
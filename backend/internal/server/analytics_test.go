@@ -0,0 +1,90 @@
+package server
+
+import (
+	"cv-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	desktopAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"
+	mobileAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile"
+)
+
+// fetchCV requests a public CV endpoint with the given headers
+func (s *testServer) fetchCV(path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return s.do(req, "")
+}
+
+func TestAnalytics(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+	viewer := s.login(t, models.RoleViewer)
+
+	if w := s.upload(editor, testPDF("Tracked version"), nil); w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+
+	w := s.fetchCV("/api/download-cv", map[string]string{"User-Agent": desktopAgent, "Referer": "https://www.linkedin.com/in/someone"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("download: %d %s", w.Code, w.Body)
+	}
+	etag := w.Header().Get("ETag")
+	s.fetchCV("/api/view-cv", map[string]string{"User-Agent": mobileAgent})
+	s.fetchCV("/api/download-cv", map[string]string{"User-Agent": "curl/8.0"})
+
+	// Neither a revalidation nor a resumed download is a new access
+	s.fetchCV("/api/download-cv", map[string]string{"User-Agent": desktopAgent, "If-None-Match": etag})
+	s.fetchCV("/api/download-cv", map[string]string{"User-Agent": desktopAgent, "Range": "bytes=10-", "If-Range": etag})
+
+	w = s.get("/api/analytics/timeseries", viewer)
+	if w.Code != http.StatusOK {
+		t.Fatalf("timeseries: %d %s", w.Code, w.Body)
+	}
+	totals, _ := decode(t, w)["totals"].(map[string]any)
+	if totals["views"] != float64(1) || totals["downloads"] != float64(1) || totals["uniqueVisitors"] != float64(1) {
+		t.Errorf("totals = %v, want 1 view, 1 download and 1 visitor", totals)
+	}
+
+	w = s.get("/api/analytics/timeseries?granularity=month&includeBots=true", viewer)
+	if totals, _ := decode(t, w)["totals"].(map[string]any); totals["downloads"] != float64(2) {
+		t.Errorf("totals with bots = %v, want 2 downloads", totals)
+	}
+
+	w = s.get("/api/analytics/referrers", viewer)
+	referrers, _ := decode(t, w)["referrers"].([]any)
+	if len(referrers) != 1 || referrers[0].(map[string]any)["referrer"] != "linkedin.com" {
+		t.Errorf("referrers = %v, want linkedin.com", referrers)
+	}
+
+	w = s.get("/api/analytics/versions", viewer)
+	versions, _ := decode(t, w)["versions"].([]any)
+	if len(versions) != 1 {
+		t.Fatalf("versions = %v, want one", versions)
+	}
+	if version := versions[0].(map[string]any); version["views"] != float64(1) || version["downloads"] != float64(1) {
+		t.Errorf("version breakdown = %v, want 1 view and 1 download", version)
+	}
+}
+
+func TestAnalyticsRejectsInvalidQueries(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	viewer := s.login(t, models.RoleViewer)
+
+	for _, path := range []string{
+		"/api/analytics/timeseries?granularity=hour",
+		"/api/analytics/timeseries?from=yesterday",
+		"/api/analytics/timeseries?from=2024-02-01&to=2024-01-01",
+		"/api/analytics/referrers?limit=0",
+		"/api/analytics/versions?to=2024-13-01",
+	} {
+		if w := s.get(path, viewer); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", path, w.Code)
+		}
+	}
+}
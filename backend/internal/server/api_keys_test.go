@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createAPIKey creates an API key for the holder of token and returns its ID
+// and the key
+func (s *testServer) createAPIKey(t *testing.T, token string, scopes ...string) (int, string) {
+	t.Helper()
+	w := s.postJSON("/api/api-keys", gin.H{"name": "ci", "scopes": scopes}, token)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create API key: %d %s", w.Code, w.Body)
+	}
+	body := decode(t, w)
+	apiKey, _ := body["apiKey"].(map[string]any)
+	id, _ := apiKey["id"].(float64)
+	key, _ := body["key"].(string)
+	return int(id), key
+}
+
+// withAPIKey sends a request authenticated with an API key
+func (s *testServer) withAPIKey(req *http.Request, key string) *httptest.ResponseRecorder {
+	req.Header.Set("Authorization", "ApiKey "+key)
+	return s.do(req, "")
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	viewer := s.login(t, models.RoleViewer)
+
+	id, key := s.createAPIKey(t, viewer, models.ScopeCVRead)
+	if key == "" {
+		t.Fatal("no key in the response")
+	}
+
+	w := s.get("/api/api-keys", viewer)
+	keys, _ := decode(t, w)["apiKeys"].([]any)
+	if len(keys) != 1 {
+		t.Fatalf("listed %d keys, want 1", len(keys))
+	}
+	listed := keys[0].(map[string]any)
+	if listed["active"] != true || fmt.Sprint(listed["scopes"]) != "[cv:read]" {
+		t.Errorf("listed key = %v", listed)
+	}
+	if _, ok := listed["key"]; ok {
+		t.Error("listing returns the key itself")
+	}
+
+	if w := s.withAPIKey(httptest.NewRequest(http.MethodGet, "/api/cv/versions", nil), key); w.Code != http.StatusOK {
+		t.Errorf("request with the key: %d %s, want 200", w.Code, w.Body)
+	}
+
+	// Other users cannot revoke the key; owners can
+	path := fmt.Sprintf("/api/api-keys/%d", id)
+	if w := s.do(httptest.NewRequest(http.MethodDelete, path, nil), s.login(t, models.RoleEditor)); w.Code != http.StatusNotFound {
+		t.Errorf("revoke by another user: %d, want 404", w.Code)
+	}
+	if w := s.do(httptest.NewRequest(http.MethodDelete, path, nil), s.login(t, models.RoleOwner)); w.Code != http.StatusOK {
+		t.Fatalf("revoke by the owner: %d %s", w.Code, w.Body)
+	}
+	if w := s.withAPIKey(httptest.NewRequest(http.MethodGet, "/api/cv/versions", nil), key); w.Code != http.StatusUnauthorized {
+		t.Errorf("request with a revoked key: %d, want 401", w.Code)
+	}
+}
+
+func TestCreateAPIKeyRejectsInvalidInput(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	for _, req := range []gin.H{
+		{"name": "", "scopes": []string{models.ScopeCVRead}},
+		{"name": "ci", "scopes": []string{}},
+		{"name": "ci", "scopes": []string{"cv:everything"}},
+		{"name": "ci", "scopes": []string{models.ScopeCVRead}, "expiresAt": "2000-01-01T00:00:00Z"},
+	} {
+		if w := s.postJSON("/api/api-keys", req, editor); w.Code != http.StatusBadRequest {
+			t.Errorf("create %v: %d %s, want 400", req, w.Code, w.Body)
+		}
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+	_, readKey := s.createAPIKey(t, editor, models.ScopeCVRead, models.ScopeContentRead)
+
+	for _, route := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/cv/versions", http.StatusOK},
+		{http.MethodGet, "/api/content/experiences", http.StatusOK},
+		// Routes of scopes the key lacks
+		{http.MethodGet, "/api/cv-stats", http.StatusForbidden},
+		{http.MethodPost, "/api/upload-cv", http.StatusForbidden},
+		{http.MethodPost, "/api/content/import", http.StatusForbidden},
+		// Routes that need a login
+		{http.MethodGet, "/api/api-keys", http.StatusForbidden},
+		{http.MethodDelete, "/api/cv", http.StatusForbidden},
+		{http.MethodPost, "/api/content/experiences", http.StatusForbidden},
+		{http.MethodPut, "/api/change-password", http.StatusForbidden},
+	} {
+		if w := s.withAPIKey(httptest.NewRequest(route.method, route.path, nil), readKey); w.Code != route.want {
+			t.Errorf("%s %s with a read-only key: %d %s, want %d", route.method, route.path, w.Code, w.Body, route.want)
+		}
+	}
+
+	_, uploadKey := s.createAPIKey(t, editor, models.ScopeCVUpload)
+	req := uploadRequest(testPDF("Uploaded by CI"), nil)
+	if w := s.withAPIKey(req, uploadKey); w.Code != http.StatusOK {
+		t.Errorf("upload with a cv:upload key: %d %s, want 200", w.Code, w.Body)
+	}
+}
+
+func TestAPIKeyCannotExceedRole(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	viewer := s.login(t, models.RoleViewer)
+
+	for _, scope := range []string{models.ScopeCVUpload, models.ScopeContentWrite} {
+		if w := s.postJSON("/api/api-keys", gin.H{"name": "ci", "scopes": []string{scope}}, viewer); w.Code != http.StatusForbidden {
+			t.Errorf("viewer creating a %s key: %d, want 403", scope, w.Code)
+		}
+	}
+
+	// A key keeps acting with its user's current role
+	editor := s.login(t, models.RoleEditor)
+	_, key := s.createAPIKey(t, editor, models.ScopeContentWrite)
+	user, err := s.users.GetUser(context.Background(), models.RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/users/%d", user.ID)
+	if w := s.sendJSON(http.MethodPut, path, gin.H{"role": models.RoleViewer}, s.login(t, models.RoleOwner)); w.Code != http.StatusOK {
+		t.Fatalf("demote editor: %d %s", w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/content/import", strings.NewReader(`{"hobbies": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	if w := s.withAPIKey(req, key); w.Code != http.StatusForbidden {
+		t.Errorf("content:write key of a demoted user: %d %s, want 403", w.Code, w.Body)
+	}
+}
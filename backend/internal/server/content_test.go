@@ -0,0 +1,124 @@
+package server
+
+import (
+	"cv-backend/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// entryTitles returns the titles of the experiences in a content response
+func entryTitles(t *testing.T, entries any) []string {
+	t.Helper()
+	list, _ := entries.([]any)
+	titles := make([]string, 0, len(list))
+	for _, entry := range list {
+		title, _ := entry.(map[string]any)["title"].(string)
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+func TestContentEntries(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	ids := map[string]uint{}
+	for _, entry := range []gin.H{
+		{"title": "Engineer", "company": "Acme", "visible": true},
+		{"title": "Intern", "company": "Initech", "visible": false},
+	} {
+		w := s.postJSON("/api/content/experiences", entry, editor)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create entry: %d %s", w.Code, w.Body)
+		}
+		id, _ := decode(t, w)["id"].(float64)
+		ids[entry["title"].(string)] = uint(id)
+	}
+
+	// Hidden entries are only listed to logged-in users
+	w := s.get("/api/cv/content", "")
+	if got := entryTitles(t, decode(t, w)["experiences"]); w.Code != http.StatusOK || len(got) != 1 || got[0] != "Engineer" {
+		t.Errorf("public content: %d %v, want only Engineer", w.Code, got)
+	}
+	w = s.get("/api/content/experiences", editor)
+	if got := entryTitles(t, decode(t, w)["entries"]); w.Code != http.StatusOK || len(got) != 2 {
+		t.Errorf("entries: %d %v, want both", w.Code, got)
+	}
+
+	// Updates keep the fields missing from the body
+	w = s.sendJSON(http.MethodPut, fmt.Sprintf("/api/content/experiences/%d", ids["Intern"]), gin.H{"visible": true}, editor)
+	if body := decode(t, w); w.Code != http.StatusOK || body["title"] != "Intern" || body["company"] != "Initech" || body["visible"] != true {
+		t.Errorf("update: %d %v", w.Code, body)
+	}
+
+	w = s.sendJSON(http.MethodPut, "/api/content/experiences/order", gin.H{"ids": []uint{ids["Intern"], ids["Engineer"]}}, editor)
+	if w.Code != http.StatusOK {
+		t.Fatalf("reorder: %d %s", w.Code, w.Body)
+	}
+	w = s.get("/api/cv/content", "")
+	if got := entryTitles(t, decode(t, w)["experiences"]); len(got) != 2 || got[0] != "Intern" {
+		t.Errorf("order after reorder = %v, want Intern first", got)
+	}
+
+	path := fmt.Sprintf("/api/content/experiences/%d", ids["Engineer"])
+	if w := s.do(httptest.NewRequest(http.MethodDelete, path, nil), editor); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(httptest.NewRequest(http.MethodDelete, path, nil), editor); w.Code != http.StatusNotFound {
+		t.Errorf("second delete: %d, want 404", w.Code)
+	}
+}
+
+func TestContentErrors(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	if w := s.get("/api/content/recipes", editor); w.Code != http.StatusNotFound {
+		t.Errorf("unknown section: %d, want 404", w.Code)
+	}
+	if w := s.postJSON("/api/content/recipes", gin.H{}, editor); w.Code != http.StatusNotFound {
+		t.Errorf("create in unknown section: %d, want 404", w.Code)
+	}
+	if w := s.sendJSON(http.MethodPut, "/api/content/experiences/abc", gin.H{}, editor); w.Code != http.StatusBadRequest {
+		t.Errorf("update with an invalid ID: %d, want 400", w.Code)
+	}
+	if w := s.sendJSON(http.MethodPut, "/api/content/experiences/42", gin.H{}, editor); w.Code != http.StatusNotFound {
+		t.Errorf("update of a missing entry: %d, want 404", w.Code)
+	}
+}
+
+func TestImportContent(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	if w := s.postJSON("/api/content/experiences", gin.H{"title": "Replaced", "company": "Old", "visible": true}, editor); w.Code != http.StatusCreated {
+		t.Fatalf("create entry: %d %s", w.Code, w.Body)
+	}
+
+	w := s.postJSON("/api/content/import", gin.H{
+		"experiences": []gin.H{
+			{"title": "Engineer", "company": "Acme", "visible": true},
+			{"title": "Lead", "company": "Acme", "visible": true},
+		},
+	}, editor)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import: %d %s", w.Code, w.Body)
+	}
+	if imported, _ := decode(t, w)["imported"].(map[string]any); imported["experiences"] != float64(2) {
+		t.Errorf("imported = %v, want 2 experiences", imported)
+	}
+
+	// The section is replaced, not appended to
+	w = s.get("/api/content/experiences", editor)
+	if got := entryTitles(t, decode(t, w)["entries"]); len(got) != 2 || got[0] != "Engineer" || got[1] != "Lead" {
+		t.Errorf("entries after import = %v, want Engineer and Lead", got)
+	}
+
+	if w := s.postJSON("/api/content/import", gin.H{"recipes": []gin.H{{}}}, editor); w.Code != http.StatusNotFound {
+		t.Errorf("import of an unknown section: %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAPIUnavailableWhileMigrating(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	s.checker.SetMigrating(true)
+
+	w := s.get("/api/download-cv", "")
+	if w.Code != http.StatusServiceUnavailable || decode(t, w)["code"] != "starting" || w.Header().Get("Retry-After") == "" {
+		t.Errorf("API while migrating: %d %s, want 503 starting with Retry-After", w.Code, w.Body)
+	}
+	if w := s.get("/livez", ""); w.Code != http.StatusOK {
+		t.Errorf("/livez while migrating: %d, want 200", w.Code)
+	}
+	if w := s.get("/readyz", ""); w.Code != http.StatusServiceUnavailable || decode(t, w)["status"] != "migrating" {
+		t.Errorf("/readyz while migrating: %d %s, want 503 migrating", w.Code, w.Body)
+	}
+
+	s.checker.SetMigrating(false)
+	if w := s.get("/api/download-cv", ""); w.Code != http.StatusNotFound {
+		t.Errorf("API after migrating: %d, want 404 without a CV", w.Code)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"cv-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// uploadLanguages uploads a CV for each language
+func (s *testServer) uploadLanguages(t *testing.T, languages ...string) {
+	t.Helper()
+	editor := s.login(t, models.RoleEditor)
+	for _, lang := range languages {
+		if w := s.upload(editor, testPDF("CV in "+lang), map[string]string{"lang": lang}); w.Code != http.StatusOK {
+			t.Fatalf("upload %s: %d %s", lang, w.Code, w.Body)
+		}
+	}
+}
+
+func TestDownloadNegotiatesLanguage(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	s.uploadLanguages(t, "en", "de", "fr-ca")
+
+	tests := []struct {
+		name, query, acceptLanguage, want string
+	}{
+		{"default", "", "", "en"},
+		{"query", "?lang=de", "fr", "de"},
+		{"query with region", "?lang=DE_at", "", "de"},
+		{"unavailable query", "?lang=es", "fr", "fr-ca"},
+		{"invalid query", "?lang=not+a+language", "", "en"},
+		{"accept-language", "", "de", "de"},
+		{"quality values", "", "fr;q=0.5, de;q=0.8, es", "de"},
+		{"base language", "", "fr-fr", "fr-ca"},
+		{"zero quality", "", "de;q=0", "en"},
+		{"unavailable accept-language", "", "es, it", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/download-cv"+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := s.do(req, "")
+			if w.Code != http.StatusOK || w.Header().Get("Content-Language") != tt.want {
+				t.Errorf("download: %d in %q, want 200 in %q", w.Code, w.Header().Get("Content-Language"), tt.want)
+			}
+			if w.Header().Get("Vary") != "Accept-Language" {
+				t.Errorf("Vary = %q, want Accept-Language", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestDownloadFallsBackWithoutDefaultLanguage(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	if w := s.get("/api/download-cv", ""); w.Code != http.StatusNotFound {
+		t.Errorf("download without a CV: %d, want 404", w.Code)
+	}
+
+	// Without a CV in the default language, any other one is served
+	s.uploadLanguages(t, "de")
+	req := httptest.NewRequest(http.MethodGet, "/api/download-cv", nil)
+	req.Header.Set("Accept-Language", "es")
+	if w := s.do(req, ""); w.Code != http.StatusOK || w.Header().Get("Content-Language") != "de" {
+		t.Errorf("download: %d in %q, want 200 in de", w.Code, w.Header().Get("Content-Language"))
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"cv-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// roleRoutes lists protected routes with the lowest role allowed to use them
+var roleRoutes = []struct {
+	method, path, role string
+}{
+	{http.MethodGet, "/api/cv-info", models.RoleViewer},
+	{http.MethodGet, "/api/cv/versions", models.RoleViewer},
+	{http.MethodGet, "/api/analytics/timeseries", models.RoleViewer},
+	{http.MethodGet, "/api/content/experiences", models.RoleViewer},
+	{http.MethodGet, "/api/cv/public-access", models.RoleViewer},
+	{http.MethodPost, "/api/upload-cv", models.RoleEditor},
+	{http.MethodDelete, "/api/cv", models.RoleEditor},
+	{http.MethodPost, "/api/cv/versions/1/restore", models.RoleEditor},
+	{http.MethodPost, "/api/cv/rollback", models.RoleEditor},
+	{http.MethodPost, "/api/cv/generated/publish", models.RoleEditor},
+	{http.MethodGet, "/api/share-links", models.RoleEditor},
+	{http.MethodPost, "/api/share-links", models.RoleEditor},
+	{http.MethodDelete, "/api/share-links/1", models.RoleEditor},
+	{http.MethodGet, "/api/share-links/1/accesses", models.RoleEditor},
+	{http.MethodPost, "/api/content/import", models.RoleEditor},
+	{http.MethodPost, "/api/content/experiences", models.RoleEditor},
+	{http.MethodDelete, "/api/content/experiences/1", models.RoleEditor},
+	{http.MethodGet, "/api/users", models.RoleOwner},
+	{http.MethodPost, "/api/users", models.RoleOwner},
+	{http.MethodDelete, "/api/users/1", models.RoleOwner},
+	{http.MethodGet, "/api/lockouts", models.RoleOwner},
+	{http.MethodGet, "/api/audit-events", models.RoleOwner},
+	{http.MethodGet, "/api/config", models.RoleOwner},
+	{http.MethodPut, "/api/cv/public-access", models.RoleOwner},
+}
+
+func TestRequireRole(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	tokens := map[string]string{}
+	for _, role := range []string{models.RoleViewer, models.RoleEditor, models.RoleOwner} {
+		tokens[role] = s.login(t, role)
+	}
+
+	for _, route := range roleRoutes {
+		for role, token := range tokens {
+			req := httptest.NewRequest(route.method, route.path, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			w := s.do(req, token)
+
+			user := models.User{Role: role}
+			if allowed := user.HasRole(route.role); allowed == (w.Code == http.StatusForbidden) {
+				t.Errorf("%s %s as %s: %d %s, allowed %v", route.method, route.path, role, w.Code, w.Body, allowed)
+			}
+		}
+	}
+}
+
+func TestViewerCannotChangeCV(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+	viewer := s.login(t, models.RoleViewer)
+
+	if w := s.upload(viewer, testPDF("Viewer version"), nil); w.Code != http.StatusForbidden {
+		t.Errorf("upload as viewer: %d, want 403", w.Code)
+	}
+	if w := s.upload(editor, testPDF("Editor version"), nil); w.Code != http.StatusOK {
+		t.Fatalf("upload as editor: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(httptest.NewRequest(http.MethodDelete, "/api/cv", nil), viewer); w.Code != http.StatusForbidden {
+		t.Errorf("delete as viewer: %d, want 403", w.Code)
+	}
+
+	// The CV is still there
+	if w := s.do(httptest.NewRequest(http.MethodGet, "/api/download-cv", nil), ""); w.Code != http.StatusOK {
+		t.Errorf("download after the viewer's delete: %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,164 @@
+// Package server wires the HTTP routes of the API to their handlers.
+package server
+
+import (
+	"cv-backend/internal/config"
+	"cv-backend/internal/handlers"
+	"cv-backend/internal/health"
+	"cv-backend/internal/middleware"
+	"cv-backend/internal/models"
+	"cv-backend/internal/scanner"
+	"cv-backend/internal/storage"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter builds the router for all API routes on top of the given
+// configuration and stores; checker runs the readiness checks. It does not
+// touch global state, so tests can build one per test with in-memory stores
+// and drive it with httptest.
+func NewRouter(cfg *config.Config, stores *storage.Stores, checker *health.Checker) (*gin.Engine, error) {
+	router := gin.New()
+	router.Use(middleware.TracingMiddleware())
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.LoggerMiddleware())
+	router.Use(middleware.RecoveryMiddleware())
+
+	// Only take the client IP from X-Forwarded-For when it was set by a known
+	// proxy; without TRUSTED_PROXIES the header is ignored
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	// Apply metrics and CORS middleware
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.CORSMiddleware())
+
+	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(cfg, stores)
+	uploadScanner, err := scanner.New(cfg.Scan)
+	if err != nil {
+		return nil, err
+	}
+	cvHandler := handlers.NewCVHandler(cfg, stores, uploadScanner)
+	contentHandler := handlers.NewContentHandler(stores)
+	analyticsHandler := handlers.NewAnalyticsHandler(stores)
+	configHandler := handlers.NewConfigHandler(cfg)
+	metricsHandler := handlers.NewMetricsHandler(cfg)
+	healthHandler := handlers.NewHealthHandler(checker)
+
+	// Liveness and readiness probes; /health is kept as an alias of /livez
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/health", healthHandler.Livez)
+
+	// Prometheus metrics, optionally protected by METRICS_TOKEN
+	router.GET("/metrics", metricsHandler.Metrics)
+
+	// Public routes; the API answers 503 until the startup migrations are done
+	api := router.Group("/api", middleware.StartupMiddleware(checker))
+	{
+		// Authentication
+		api.POST("/login", authHandler.Login)
+		api.POST("/login/2fa", authHandler.LoginTwoFactor)
+		api.POST("/refresh", authHandler.Refresh)
+		api.POST("/logout", authHandler.Logout)
+
+		// CV download and viewing (public)
+		api.GET("/download-cv", cvHandler.DownloadCV)
+		api.HEAD("/download-cv", cvHandler.DownloadCV)
+		api.GET("/view-cv", cvHandler.ViewCV)
+		api.HEAD("/view-cv", cvHandler.ViewCV)
+
+		// Structured CV content (public)
+		api.GET("/cv/content", contentHandler.GetPublicContent)
+		api.GET("/cv/generated.pdf", cvHandler.GeneratedCV)
+
+		// Private share links
+		api.GET("/share/:token", cvHandler.ShareLinkInfo)
+		api.GET("/share/:token/download", cvHandler.ShareLinkDownload)
+		api.HEAD("/share/:token/download", cvHandler.ShareLinkDownload)
+		api.GET("/share/:token/view", cvHandler.ShareLinkView)
+		api.HEAD("/share/:token/view", cvHandler.ShareLinkView)
+	}
+
+	// Protected routes (require authentication)
+	protected := api.Group("/")
+	protected.Use(middleware.AuthMiddleware(stores.Users, stores.APIKeys))
+
+	// Viewers can read everything; editors change the CV and owners manage access
+	editor := middleware.RequireRole(models.RoleEditor)
+	owner := middleware.RequireRole(models.RoleOwner)
+	{
+		// Auth verification
+		protected.GET("/verify", authHandler.VerifyToken)
+		protected.POST("/logout-all", authHandler.LogoutAll)
+
+		// API keys for scripts and CI
+		protected.GET("/api-keys", authHandler.ListAPIKeys)
+		protected.POST("/api-keys", authHandler.CreateAPIKey)
+		protected.DELETE("/api-keys/:id", authHandler.RevokeAPIKey)
+
+		// User management
+		protected.GET("/users", owner, authHandler.ListUsers)
+		protected.POST("/users", owner, authHandler.CreateUser)
+		protected.PUT("/users/:id", owner, authHandler.UpdateUser)
+		protected.DELETE("/users/:id", owner, authHandler.DeleteUser)
+
+		// Login lockouts and audit trail
+		protected.GET("/lockouts", owner, authHandler.ListLockouts)
+		protected.POST("/lockouts/clear", owner, authHandler.ClearLockout)
+		protected.GET("/audit-events", owner, authHandler.ListAuditEvents)
+
+		// Settings in effect, with secrets redacted
+		protected.GET("/config", owner, configHandler.GetConfig)
+
+		// Two-factor authentication
+		protected.GET("/2fa", authHandler.TwoFactorStatus)
+		protected.POST("/2fa/enroll", authHandler.EnrollTwoFactor)
+		protected.POST("/2fa/activate", authHandler.ActivateTwoFactor)
+		protected.POST("/2fa/disable", authHandler.DisableTwoFactor)
+		protected.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+
+		// Password management
+		protected.PUT("/change-password", authHandler.ChangePassword)
+
+		// CV management
+		protected.POST("/upload-cv", editor, cvHandler.UploadCV)
+		protected.GET("/cv-info", cvHandler.GetCVInfo)
+		protected.GET("/cv-stats", cvHandler.GetStats)
+
+		// Download and view analytics
+		protected.GET("/analytics/timeseries", analyticsHandler.Timeseries)
+		protected.GET("/analytics/referrers", analyticsHandler.TopReferrers)
+		protected.GET("/analytics/versions", analyticsHandler.VersionBreakdown)
+		protected.DELETE("/cv", editor, cvHandler.DeleteCV)
+
+		// CV version history
+		protected.GET("/cv/versions", cvHandler.ListVersions)
+		protected.GET("/cv/versions/:id/download", cvHandler.DownloadVersion)
+		protected.POST("/cv/versions/:id/restore", editor, cvHandler.RestoreVersion)
+		protected.POST("/cv/rollback", editor, cvHandler.RollbackCV)
+		protected.POST("/cv/generated/publish", editor, cvHandler.PublishGeneratedCV)
+
+		// Share links and public access
+		// Listing exposes the link tokens, so it needs the role that creates them
+		protected.GET("/share-links", editor, cvHandler.ListShareLinks)
+		protected.POST("/share-links", editor, cvHandler.CreateShareLink)
+		protected.DELETE("/share-links/:id", editor, cvHandler.RevokeShareLink)
+		protected.GET("/share-links/:id/accesses", editor, cvHandler.ShareLinkAccesses)
+		protected.GET("/cv/public-access", cvHandler.GetPublicAccess)
+		protected.PUT("/cv/public-access", owner, cvHandler.SetPublicAccess)
+
+		// Structured CV content management
+		protected.POST("/content/import", editor, contentHandler.ImportContent)
+		protected.GET("/content/:section", contentHandler.ListEntries)
+		protected.POST("/content/:section", editor, contentHandler.CreateEntry)
+		protected.PUT("/content/:section/order", editor, contentHandler.ReorderEntries)
+		protected.PUT("/content/:section/:id", editor, contentHandler.UpdateEntry)
+		protected.DELETE("/content/:section/:id", editor, contentHandler.DeleteEntry)
+	}
+
+	return router, nil
+}
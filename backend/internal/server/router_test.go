@@ -0,0 +1,498 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"cv-backend/internal/auth"
+	"cv-backend/internal/config"
+	"cv-backend/internal/health"
+	"cv-backend/internal/migrations"
+	"cv-backend/internal/models"
+	"cv-backend/internal/storage"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testPassword  = "correct horse battery"
+	testJWTSecret = "0123456789abcdef0123456789abcdef"
+)
+
+// testServer is a router on in-memory stores. CVs and users live in the
+// memory stores or in the database; the other storages always use an
+// in-memory SQLite database.
+type testServer struct {
+	router  *gin.Engine
+	stores  *storage.Stores
+	users   storage.UserStore
+	cvs     storage.CVStore
+	checker *health.Checker
+}
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		Mode: gin.TestMode,
+		Auth: config.AuthConfig{
+			JWTSecret:       testJWTSecret,
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: time.Hour,
+			TOTPIssuer:      "CV Admin",
+			AdminUsername:   "admin",
+		},
+		CV: config.CVConfig{
+			DefaultLanguage: "en",
+			OwnerName:       "Test Owner",
+		},
+		Scan: config.ScanConfig{
+			FailMode: "closed",
+			Timeout:  5 * time.Second,
+		},
+	}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	// Every connection to :memory: is a database of its own
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	return db
+}
+
+// newTestServer returns a test server whose users and CVs live in the memory
+// stores
+func newTestServer(t *testing.T, cfg *config.Config) *testServer {
+	t.Helper()
+	return buildTestServer(t, cfg, false)
+}
+
+// newDBTestServer returns a test server whose users and CVs live in the
+// database, with CV content in a temporary directory, for endpoints whose
+// storages read those tables themselves, such as 2FA, account lockouts and
+// analytics
+func newDBTestServer(t *testing.T, cfg *config.Config) *testServer {
+	t.Helper()
+	return buildTestServer(t, cfg, true)
+}
+
+func buildTestServer(t *testing.T, cfg *config.Config, inDB bool) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	auth.Configure(cfg.Auth.JWTSecret, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+
+	blobs, err := storage.NewFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := storage.NewStores(newTestDB(t), blobs)
+	if !inDB {
+		stores.Users = storage.NewMemoryUserStore()
+		stores.CVs = storage.NewMemoryCVStore()
+	}
+
+	ctx := context.Background()
+	for _, role := range []string{models.RoleOwner, models.RoleEditor, models.RoleViewer} {
+		if _, err := stores.Users.CreateUser(ctx, role, testPassword, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checker := health.NewChecker(time.Second)
+	router, err := NewRouter(cfg, stores, checker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testServer{router: router, stores: stores, users: stores.Users, cvs: stores.CVs, checker: checker}
+}
+
+// do sends a request to the router; a non-empty token is sent as bearer token
+func (s *testServer) do(req *http.Request, token string) *httptest.ResponseRecorder {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// get sends a GET request to the router
+func (s *testServer) get(path, token string) *httptest.ResponseRecorder {
+	return s.do(httptest.NewRequest(http.MethodGet, path, nil), token)
+}
+
+func (s *testServer) postJSON(path string, body any, token string) *httptest.ResponseRecorder {
+	return s.sendJSON(http.MethodPost, path, body, token)
+}
+
+// sendJSON sends a request with a JSON body to the router
+func (s *testServer) sendJSON(method, path string, body any, token string) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	return s.do(req, token)
+}
+
+// login returns an access token for the user
+func (s *testServer) login(t *testing.T, username string) string {
+	t.Helper()
+	w := s.postJSON("/api/login", gin.H{"username": username, "password": testPassword}, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("login as %s: %d %s", username, w.Code, w.Body)
+	}
+	var resp models.LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Token == "" {
+		t.Fatalf("login as %s: no token in %s", username, w.Body)
+	}
+	return resp.Token
+}
+
+// upload posts a file to /api/upload-cv with the given form fields
+func (s *testServer) upload(token string, file []byte, fields map[string]string) *httptest.ResponseRecorder {
+	return s.do(uploadRequest(file, fields), token)
+}
+
+// uploadRequest builds the multipart request of an upload
+func uploadRequest(file []byte, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("cv", "cv.pdf")
+	part.Write(file)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/upload-cv", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// decode unmarshals a JSON response body
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body, err)
+	}
+	return body
+}
+
+// testPDF returns a minimal well-formed PDF whose page content contains text
+func testPDF(text string) []byte {
+	return testPDFWithCatalog("", text)
+}
+
+// testPDFWithCatalog is testPDF with extra entries in the document catalog
+func testPDFWithCatalog(catalog, text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R " + catalog + ">>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+
+	token := s.login(t, models.RoleOwner)
+	req := httptest.NewRequest(http.MethodGet, "/api/verify", nil)
+	if w := s.do(req, token); w.Code != http.StatusOK {
+		t.Errorf("verify with fresh token: %d %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name string
+		body any
+		want int
+	}{
+		{"wrong password", gin.H{"username": "owner", "password": "wrong password"}, http.StatusUnauthorized},
+		{"unknown user", gin.H{"username": "nobody", "password": testPassword}, http.StatusUnauthorized},
+		{"missing password", gin.H{"username": "owner"}, http.StatusBadRequest},
+		{"not JSON", "username=owner", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.postJSON("/api/login", tt.body, "")
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if decode(t, w)["token"] != nil {
+				t.Error("failed login returned a token")
+			}
+		})
+	}
+}
+
+func TestLoginThrottled(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 30; i++ {
+		w = s.postJSON("/api/login", gin.H{"username": "owner", "password": "wrong password"}, "")
+		if w.Code == http.StatusTooManyRequests {
+			break
+		}
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("repeated failures were not throttled: %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("throttled response has no Retry-After header")
+	}
+
+	// The right password does not get through while throttled either
+	if w := s.postJSON("/api/login", gin.H{"username": "owner", "password": testPassword}, ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("login while throttled: %d, want 429", w.Code)
+	}
+}
+
+func TestAuthFailures(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	viewer := s.login(t, models.RoleViewer)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		want   int
+	}{
+		{"no token", http.MethodGet, "/api/verify", "", http.StatusUnauthorized},
+		{"malformed header", http.MethodGet, "/api/verify", "Token abc", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "/api/verify", "Bearer not-a-jwt", http.StatusUnauthorized},
+		{"viewer uploads", http.MethodPost, "/api/upload-cv", "Bearer " + viewer, http.StatusForbidden},
+		{"viewer deletes", http.MethodDelete, "/api/cv", "Bearer " + viewer, http.StatusForbidden},
+		{"viewer lists users", http.MethodGet, "/api/users", "Bearer " + viewer, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if w := s.do(req, ""); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestTokenRevokedByLogoutAll(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	token := s.login(t, models.RoleOwner)
+
+	if w := s.do(httptest.NewRequest(http.MethodPost, "/api/logout-all", nil), token); w.Code != http.StatusOK {
+		t.Fatalf("logout-all: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(httptest.NewRequest(http.MethodGet, "/api/verify", nil), token); w.Code != http.StatusUnauthorized {
+		t.Errorf("verify after logout-all: %d, want 401", w.Code)
+	}
+}
+
+func TestRefreshTokenReuseEndsSession(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+
+	refresh := func(token string) (int, string) {
+		w := s.postJSON("/api/refresh", gin.H{"refresh_token": token}, "")
+		next, _ := decode(t, w)["refresh_token"].(string)
+		return w.Code, next
+	}
+
+	w := s.postJSON("/api/login", gin.H{"username": models.RoleOwner, "password": testPassword}, "")
+	stolen, _ := decode(t, w)["refresh_token"].(string)
+	status, current := refresh(stolen)
+	if status != http.StatusOK || current == "" {
+		t.Fatalf("refresh: %d", status)
+	}
+
+	if status, _ := refresh(stolen); status != http.StatusUnauthorized {
+		t.Errorf("reused refresh token: %d, want 401", status)
+	}
+	if status, _ := refresh(current); status != http.StatusUnauthorized {
+		t.Errorf("latest refresh token after reuse: %d, want 401", status)
+	}
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	if w := s.do(httptest.NewRequest(http.MethodGet, "/api/download-cv", nil), ""); w.Code != http.StatusNotFound {
+		t.Errorf("download before upload: %d, want 404", w.Code)
+	}
+
+	pdf := testPDF("Version one")
+	w := s.upload(editor, pdf, map[string]string{"note": "First version"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+	body := decode(t, w)
+	if body["unchanged"] != false || body["changeNote"] != "First version" || body["language"] != "en" {
+		t.Errorf("upload response = %v", body)
+	}
+
+	w = s.do(httptest.NewRequest(http.MethodGet, "/api/download-cv", nil), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("download: %d %s", w.Code, w.Body)
+	}
+	if !bytes.Equal(w.Body.Bytes(), pdf) {
+		t.Error("downloaded file differs from the upload")
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/pdf" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("download has no ETag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/download-cv", nil)
+	req.Header.Set("If-None-Match", etag)
+	if w := s.do(req, ""); w.Code != http.StatusNotModified {
+		t.Errorf("conditional download: %d, want 304", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/download-cv", nil)
+	req.Header.Set("Range", "bytes=0-7")
+	if w := s.do(req, ""); w.Code != http.StatusPartialContent || w.Body.String() != "%PDF-1.7" {
+		t.Errorf("range download: %d %q", w.Code, w.Body)
+	}
+}
+
+func TestUploadUnchanged(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+	pdf := testPDF("Same content")
+
+	if w := s.upload(editor, pdf, nil); w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+
+	w := s.upload(editor, pdf, nil)
+	if w.Code != http.StatusOK || decode(t, w)["unchanged"] != true {
+		t.Errorf("repeated upload: %d %s, want unchanged", w.Code, w.Body)
+	}
+
+	w = s.upload(editor, pdf, map[string]string{"note": "Fixed a typo"})
+	if w.Code != http.StatusConflict || decode(t, w)["code"] != "cv_unchanged" {
+		t.Errorf("repeated upload with a note: %d %s, want 409 cv_unchanged", w.Code, w.Body)
+	}
+
+	versions, err := s.cvs.ListVersions(context.Background())
+	if err != nil || len(versions) != 1 {
+		t.Errorf("versions = %d, %v, want 1", len(versions), err)
+	}
+}
+
+func TestUploadRejectsInvalidFiles(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+	pdf := testPDF("Content")
+
+	tests := []struct {
+		name string
+		file []byte
+		want string
+	}{
+		{"not a PDF", []byte("just some text, not a PDF at all"), "not_pdf"},
+		{"truncated", pdf[:len(pdf)-40], "pdf_truncated"},
+		{"JavaScript", testPDFWithCatalog("/OpenAction << /S /JavaScript /JS (app.alert(1)) >> ", "Content"), "pdf_javascript"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.upload(editor, tt.file, nil)
+			if w.Code != http.StatusBadRequest || decode(t, w)["code"] != tt.want {
+				t.Errorf("upload: %d %s, want 400 %s", w.Code, w.Body, tt.want)
+			}
+		})
+	}
+
+	if w := s.upload(editor, pdf, map[string]string{"lang": "not a language"}); w.Code != http.StatusBadRequest {
+		t.Errorf("upload with invalid language: %d, want 400", w.Code)
+	}
+
+	versions, err := s.cvs.ListVersions(context.Background())
+	if err != nil || len(versions) != 0 {
+		t.Errorf("versions = %d, %v, want none", len(versions), err)
+	}
+}
+
+func TestUploadLargeFiles(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	// Files above the in-memory form limit are spooled to disk
+	pdf := testPDF(strings.Repeat("x", 3<<20))
+	if w := s.upload(editor, pdf, nil); w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+	if w := s.get("/api/download-cv", ""); w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), pdf) {
+		t.Errorf("download: %d, %d bytes, want the %d uploaded", w.Code, w.Body.Len(), len(pdf))
+	}
+
+	for _, size := range []int{10<<20 + 1<<10, 12 << 20} {
+		w := s.upload(editor, testPDF(strings.Repeat("x", size)), nil)
+		if w.Code != http.StatusRequestEntityTooLarge || decode(t, w)["code"] != "file_too_large" {
+			t.Errorf("upload of %d bytes: %d %s, want 413 file_too_large", size, w.Code, w.Body)
+		}
+	}
+}
+
+func TestDownloadRequiresPublicAccess(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+	if w := s.upload(editor, testPDF("Content"), nil); w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+
+	if err := s.stores.Settings.SetBool(context.Background(), storage.SettingPublicAccess, false); err != nil {
+		t.Fatal(err)
+	}
+	if w := s.do(httptest.NewRequest(http.MethodGet, "/api/download-cv", nil), ""); w.Code != http.StatusForbidden {
+		t.Errorf("download without public access: %d, want 403", w.Code)
+	}
+}
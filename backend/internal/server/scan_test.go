@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"cv-backend/internal/config"
+	"cv-backend/internal/models"
+	"cv-backend/internal/scanner/clamdtest"
+	"cv-backend/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newScanningServer returns a test server whose uploads are scanned by a
+// fake clamd, and an editor token
+func newScanningServer(t *testing.T, failMode string) (*testServer, *clamdtest.Server, string) {
+	t.Helper()
+	clamd := clamdtest.NewServer()
+	t.Cleanup(clamd.Close)
+
+	cfg := newTestConfig()
+	cfg.Scan = config.ScanConfig{ClamdAddress: clamd.Addr, FailMode: failMode, Timeout: cfg.Scan.Timeout}
+	s := newTestServer(t, cfg)
+	return s, clamd, s.login(t, models.RoleEditor)
+}
+
+// publish publishes the generated CV
+func (s *testServer) publish(token, note string) *httptest.ResponseRecorder {
+	return s.postJSON("/api/cv/generated/publish", gin.H{"note": note}, token)
+}
+
+// scanStatus returns the scan status of the only stored version
+func (s *testServer) scanStatus(t *testing.T) (string, bool) {
+	t.Helper()
+	versions, err := s.cvs.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("stored %d versions, want 1", len(versions))
+	}
+	return versions[0].ScanStatus, versions[0].IsCurrent
+}
+
+// submitters upload a file or publish the generated CV; generated PDFs get
+// the content pattern that the fake clamd is told to flag
+var submitters = []struct {
+	name   string
+	submit func(s *testServer, token, content string) *httptest.ResponseRecorder
+	flag   func(clamd *clamdtest.Server)
+}{
+	{
+		name: "upload",
+		submit: func(s *testServer, token, content string) *httptest.ResponseRecorder {
+			return s.upload(token, testPDF(content), nil)
+		},
+		flag: func(*clamdtest.Server) {},
+	},
+	{
+		name: "publish",
+		submit: func(s *testServer, token, content string) *httptest.ResponseRecorder {
+			return s.publish(token, "")
+		},
+		flag: func(clamd *clamdtest.Server) { clamd.AddSignature("%PDF-", "Test-Signature") },
+	},
+}
+
+func TestScanClean(t *testing.T) {
+	for _, sub := range submitters {
+		t.Run(sub.name, func(t *testing.T) {
+			s, clamd, token := newScanningServer(t, "closed")
+
+			w := sub.submit(s, token, "Clean content")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d %s, want 200", w.Code, w.Body)
+			}
+			if clamd.Scans() != 1 {
+				t.Errorf("scanned %d times, want 1", clamd.Scans())
+			}
+			if status, current := s.scanStatus(t); status != models.ScanStatusClean || !current {
+				t.Errorf("stored as %s, current %v; want clean and current", status, current)
+			}
+		})
+	}
+}
+
+func TestScanInfected(t *testing.T) {
+	for _, sub := range submitters {
+		t.Run(sub.name, func(t *testing.T) {
+			s, clamd, token := newScanningServer(t, "closed")
+			sub.flag(clamd)
+
+			w := sub.submit(s, token, clamdtest.EICAR)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d %s, want 422", w.Code, w.Body)
+			}
+			if body := decode(t, w); body["code"] != "malware_detected" || body["signature"] == "" {
+				t.Errorf("response = %v", body)
+			}
+			if status, current := s.scanStatus(t); status != models.ScanStatusQuarantined || current {
+				t.Errorf("stored as %s, current %v; want quarantined and not current", status, current)
+			}
+
+			// The flagged file is not served
+			if w := s.do(httptest.NewRequest(http.MethodGet, "/api/download-cv", nil), ""); w.Code != http.StatusNotFound {
+				t.Errorf("download: %d, want 404", w.Code)
+			}
+
+			events, err := s.stores.Audit.ListEvents(context.Background(), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 || events[0].Action != storage.AuditCVQuarantined || events[0].Actor != models.RoleEditor {
+				t.Errorf("audit events = %+v, want one cv_quarantined by editor", events)
+			}
+		})
+	}
+}
+
+func TestScannerDown(t *testing.T) {
+	for _, sub := range submitters {
+		t.Run(sub.name+" fail closed", func(t *testing.T) {
+			s, clamd, token := newScanningServer(t, "closed")
+			clamd.Close()
+
+			w := sub.submit(s, token, "Content")
+			if w.Code != http.StatusServiceUnavailable || decode(t, w)["code"] != "scan_unavailable" {
+				t.Fatalf("status = %d %s, want 503 scan_unavailable", w.Code, w.Body)
+			}
+			versions, err := s.cvs.ListVersions(context.Background())
+			if err != nil || len(versions) != 0 {
+				t.Errorf("stored %d versions, %v; want none", len(versions), err)
+			}
+		})
+
+		t.Run(sub.name+" fail open", func(t *testing.T) {
+			s, clamd, token := newScanningServer(t, "open")
+			clamd.SetFailing(true)
+
+			w := sub.submit(s, token, "Content")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d %s, want 200", w.Code, w.Body)
+			}
+			if status, current := s.scanStatus(t); status != models.ScanStatusUnscanned || !current {
+				t.Errorf("stored as %s, current %v; want unscanned and current", status, current)
+			}
+		})
+	}
+}
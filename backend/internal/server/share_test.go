@@ -0,0 +1,180 @@
+package server
+
+import (
+	"cv-backend/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createShareLink creates a share link as the editor and returns it
+func (s *testServer) createShareLink(t *testing.T, editor string, link gin.H) map[string]any {
+	t.Helper()
+	w := s.postJSON("/api/share-links", link, editor)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create share link: %d %s", w.Code, w.Body)
+	}
+	return decode(t, w)
+}
+
+// uploadAndShare uploads a CV and creates a share link to it, returning the
+// link's path
+func (s *testServer) uploadAndShare(t *testing.T, link gin.H) string {
+	t.Helper()
+	editor := s.login(t, models.RoleEditor)
+	if w := s.upload(editor, testPDF("Shared version"), nil); w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+	path, _ := s.createShareLink(t, editor, link)["path"].(string)
+	return path
+}
+
+// fetchShared requests a share link endpoint with the given headers
+func (s *testServer) fetchShared(path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return s.do(req, "")
+}
+
+func TestShareLinkDownloadCap(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	path := s.uploadAndShare(t, gin.H{"label": "recruiter", "maxDownloads": 2}) + "/download"
+
+	w := s.fetchShared(path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first download: %d %s", w.Code, w.Body)
+	}
+	etag := w.Header().Get("ETag")
+
+	// Revalidating and resuming the same download are free
+	if w := s.fetchShared(path, map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Errorf("revalidation: %d, want 304", w.Code)
+	}
+	if w := s.fetchShared(path, map[string]string{"Range": "bytes=1-", "If-Range": etag}); w.Code != http.StatusPartialContent {
+		t.Errorf("resumed download: %d, want 206", w.Code)
+	}
+
+	// A Range request without a matching If-Range is a new download
+	if w := s.fetchShared(path, map[string]string{"Range": "bytes=1-"}); w.Code != http.StatusPartialContent {
+		t.Fatalf("second download: %d %s, want 206", w.Code, w.Body)
+	}
+	for _, headers := range []map[string]string{
+		nil,
+		{"Range": "bytes=1-"},
+		{"Range": "bytes=0-"},
+		{"Range": "bytes=1-", "If-Range": `"stale"`},
+	} {
+		if w := s.fetchShared(path, headers); w.Code != http.StatusGone {
+			t.Errorf("download with %v after the cap: %d, want 410", headers, w.Code)
+		}
+	}
+}
+
+func TestShareLinkLifecycle(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+	if w := s.upload(editor, testPDF("Shared version"), nil); w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+
+	link := s.createShareLink(t, editor, gin.H{"label": "Acme recruiter", "maxDownloads": 5})
+	path, _ := link["path"].(string)
+	id, _ := link["id"].(float64)
+
+	w := s.get(path, "")
+	if body := decode(t, w); w.Code != http.StatusOK || body["passwordRequired"] != false || body["remainingDownloads"] != float64(5) {
+		t.Errorf("info: %d %v", w.Code, body)
+	}
+	w = s.fetchShared(path+"/view", nil)
+	if w.Code != http.StatusOK || w.Header().Get("X-Robots-Tag") != "noindex" {
+		t.Errorf("view: %d, X-Robots-Tag %q", w.Code, w.Header().Get("X-Robots-Tag"))
+	}
+
+	w = s.get("/api/share-links", editor)
+	links, _ := decode(t, w)["shareLinks"].([]any)
+	if len(links) != 1 {
+		t.Fatalf("listed %d links, want 1", len(links))
+	}
+	if listed := links[0].(map[string]any); listed["label"] != "Acme recruiter" || listed["downloadCount"] != float64(1) || listed["status"] != models.ShareLinkActive {
+		t.Errorf("listed link = %v", listed)
+	}
+
+	w = s.get(fmt.Sprintf("/api/share-links/%d/accesses", int(id)), editor)
+	if body := decode(t, w); w.Code != http.StatusOK || body["count"] != float64(1) {
+		t.Errorf("accesses: %d %v, want one", w.Code, body)
+	}
+
+	revoke := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/share-links/%d", int(id)), nil)
+	if w := s.do(revoke, editor); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+	for _, endpoint := range []string{path, path + "/download", path + "/view"} {
+		if w := s.get(endpoint, ""); w.Code != http.StatusGone {
+			t.Errorf("%s after revoking: %d, want 410", endpoint, w.Code)
+		}
+	}
+	if w := s.get("/api/share/unknown", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown token: %d, want 404", w.Code)
+	}
+}
+
+func TestShareLinkPassword(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	path := s.uploadAndShare(t, gin.H{"password": "a long password"}) + "/download"
+
+	if w := s.fetchShared(path, nil); w.Code != http.StatusUnauthorized || decode(t, w)["passwordRequired"] != true {
+		t.Errorf("download without password: %d %s, want 401", w.Code, w.Body)
+	}
+	if w := s.fetchShared(path, map[string]string{"X-Share-Password": "wrong"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("download with wrong password: %d, want 401", w.Code)
+	}
+	if w := s.fetchShared(path, map[string]string{"X-Share-Password": "a long password"}); w.Code != http.StatusOK {
+		t.Errorf("download with password: %d %s, want 200", w.Code, w.Body)
+	}
+}
+
+func TestCreateShareLinkRejectsInvalidInput(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	for _, link := range []gin.H{
+		{"maxDownloads": -1},
+		{"expiresAt": time.Now().Add(-time.Hour)},
+		{"password": "short"},
+		{"lang": "not a language"},
+		{"versionId": 1, "lang": "en"},
+	} {
+		if w := s.postJSON("/api/share-links", link, editor); w.Code != http.StatusBadRequest {
+			t.Errorf("create %v: %d %s, want 400", link, w.Code, w.Body)
+		}
+	}
+	if w := s.postJSON("/api/share-links", gin.H{"versionId": 42}, editor); w.Code != http.StatusNotFound {
+		t.Errorf("create for a missing version: %d, want 404", w.Code)
+	}
+}
+
+func TestShareLinksWithoutPublicAccess(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	path := s.uploadAndShare(t, gin.H{})
+	owner := s.login(t, models.RoleOwner)
+
+	if w := s.sendJSON(http.MethodPut, "/api/cv/public-access", gin.H{"enabled": false}, owner); w.Code != http.StatusOK {
+		t.Fatalf("turn off public access: %d %s", w.Code, w.Body)
+	}
+	if w := s.get("/api/cv/public-access", owner); decode(t, w)["enabled"] != false {
+		t.Errorf("public access: %s, want disabled", w.Body)
+	}
+
+	if w := s.get("/api/download-cv", ""); w.Code != http.StatusForbidden {
+		t.Errorf("public download: %d, want 403", w.Code)
+	}
+	if w := s.get(path+"/download", ""); w.Code != http.StatusOK {
+		t.Errorf("share link download: %d, want 200", w.Code)
+	}
+}
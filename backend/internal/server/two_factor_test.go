@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"cv-backend/internal/models"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// totpCode computes the TOTP code of a base32 secret for the 30-second step
+// at the given offset from now, as an authenticator app would
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(time.Now().Unix()/30+offset))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	i := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[i:i+4])&0x7fffffff)%1000000)
+}
+
+// enableTwoFactor turns on 2FA for the owner and returns the secret and an
+// access token
+func enableTwoFactor(t *testing.T, s *testServer) (string, string) {
+	t.Helper()
+	token := s.login(t, models.RoleOwner)
+
+	w := s.postJSON("/api/2fa/enroll", gin.H{"current_password": testPassword}, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enroll: %d %s", w.Code, w.Body)
+	}
+	secret, _ := decode(t, w)["secret"].(string)
+
+	w = s.postJSON("/api/2fa/activate", gin.H{"current_password": testPassword, "code": totpCode(t, secret, 0)}, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("activate: %d %s", w.Code, w.Body)
+	}
+	return secret, token
+}
+
+func TestEnrollTwoFactorRequiresPassword(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	token := s.login(t, models.RoleOwner)
+
+	if w := s.postJSON("/api/2fa/enroll", gin.H{}, token); w.Code != http.StatusBadRequest {
+		t.Errorf("enroll without password: %d, want 400", w.Code)
+	}
+	if w := s.postJSON("/api/2fa/enroll", gin.H{"current_password": "wrong password"}, token); w.Code != http.StatusUnauthorized {
+		t.Errorf("enroll with wrong password: %d, want 401", w.Code)
+	}
+
+	w := s.postJSON("/api/2fa/enroll", gin.H{"current_password": testPassword}, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enroll: %d %s", w.Code, w.Body)
+	}
+	secret, _ := decode(t, w)["secret"].(string)
+
+	w = s.postJSON("/api/2fa/activate", gin.H{"current_password": "wrong password", "code": totpCode(t, secret, 0)}, token)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("activate with wrong password: %d, want 401", w.Code)
+	}
+	w = s.postJSON("/api/2fa/activate", gin.H{"current_password": testPassword, "code": totpCode(t, secret, 0)}, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("activate: %d %s", w.Code, w.Body)
+	}
+	if codes, _ := decode(t, w)["recovery_codes"].([]any); len(codes) == 0 {
+		t.Error("activation returned no recovery codes")
+	}
+
+	// Logging in now needs the second step
+	w = s.postJSON("/api/login", gin.H{"username": models.RoleOwner, "password": testPassword}, "")
+	challenge, _ := decode(t, w)["challenge_token"].(string)
+	if w.Code != http.StatusOK || challenge == "" {
+		t.Fatalf("login with 2FA: %d %s, want a challenge", w.Code, w.Body)
+	}
+	w = s.postJSON("/api/login/2fa", gin.H{"challenge_token": challenge, "code": totpCode(t, secret, 1)}, "")
+	if w.Code != http.StatusOK || decode(t, w)["token"] == nil {
+		t.Errorf("second login step: %d %s", w.Code, w.Body)
+	}
+}
+
+// guessCodes sends wrong codes until the server stops answering 401
+func guessCodes(t *testing.T, send func(code string) int) int {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		if status := send(fmt.Sprintf("%06d", i)); status != http.StatusUnauthorized {
+			return status
+		}
+	}
+	return http.StatusUnauthorized
+}
+
+func TestRegenerateRecoveryCodesThrottlesCodes(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	secret, token := enableTwoFactor(t, s)
+
+	status := guessCodes(t, func(code string) int {
+		return s.postJSON("/api/2fa/recovery-codes", gin.H{"code": code}, token).Code
+	})
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("guessing codes ended with %d, want 429", status)
+	}
+
+	// The right code is refused as well while the account is locked
+	if w := s.postJSON("/api/2fa/recovery-codes", gin.H{"code": totpCode(t, secret, 1)}, token); w.Code != http.StatusTooManyRequests {
+		t.Errorf("right code while throttled: %d, want 429", w.Code)
+	}
+}
+
+func TestDisableTwoFactorThrottlesCodesAfterRightPassword(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	_, token := enableTwoFactor(t, s)
+
+	// A right password must not reset the count between wrong codes
+	status := guessCodes(t, func(code string) int {
+		return s.postJSON("/api/2fa/disable", gin.H{"password": testPassword, "code": code}, token).Code
+	})
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("guessing codes ended with %d, want 429", status)
+	}
+
+	user, err := s.users.GetUser(context.Background(), models.RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.TOTPEnabled {
+		t.Error("2FA was turned off")
+	}
+}
+
+func TestTwoFactorRecoveryCodes(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	secret, token := enableTwoFactor(t, s)
+
+	w := s.postJSON("/api/2fa/recovery-codes", gin.H{"code": totpCode(t, secret, 1)}, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("regenerate recovery codes: %d %s", w.Code, w.Body)
+	}
+	list, _ := decode(t, w)["recovery_codes"].([]any)
+	if len(list) < 2 {
+		t.Fatalf("got %d recovery codes, want at least 2", len(list))
+	}
+	codes := make([]string, len(list))
+	for i, code := range list {
+		codes[i], _ = code.(string)
+	}
+
+	// A recovery code replaces the TOTP code once
+	loginWith := func(code string) int {
+		w := s.postJSON("/api/login", gin.H{"username": models.RoleOwner, "password": testPassword}, "")
+		challenge, _ := decode(t, w)["challenge_token"].(string)
+		return s.postJSON("/api/login/2fa", gin.H{"challenge_token": challenge, "code": code}, "").Code
+	}
+	if status := loginWith(codes[0]); status != http.StatusOK {
+		t.Fatalf("login with a recovery code: %d", status)
+	}
+	if status := loginWith(codes[0]); status != http.StatusUnauthorized {
+		t.Errorf("login with a used recovery code: %d, want 401", status)
+	}
+
+	w = s.get("/api/2fa", token)
+	if body := decode(t, w); body["enabled"] != true || body["recovery_codes_remaining"] != float64(len(codes)-1) {
+		t.Errorf("status = %v, want enabled with %d codes left", body, len(codes)-1)
+	}
+
+	if w := s.postJSON("/api/2fa/disable", gin.H{"password": testPassword, "code": codes[1]}, token); w.Code != http.StatusOK {
+		t.Fatalf("disable: %d %s", w.Code, w.Body)
+	}
+	w = s.postJSON("/api/login", gin.H{"username": models.RoleOwner, "password": testPassword}, "")
+	if body := decode(t, w); w.Code != http.StatusOK || body["token"] == nil {
+		t.Errorf("login after disabling 2FA: %d %v, want a token", w.Code, body)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserManagement(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	owner := s.login(t, models.RoleOwner)
+
+	w := s.postJSON("/api/users", gin.H{"username": "new.hire", "password": "initial password", "role": models.RoleEditor}, owner)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create user: %d %s", w.Code, w.Body)
+	}
+	id, _ := decode(t, w)["id"].(float64)
+	path := fmt.Sprintf("/api/users/%d", int(id))
+
+	if w := s.postJSON("/api/users", gin.H{"username": "new.hire", "password": "another password", "role": models.RoleViewer}, owner); w.Code != http.StatusConflict {
+		t.Errorf("duplicate user: %d, want 409", w.Code)
+	}
+
+	// New users have to change the initial password
+	w = s.postJSON("/api/login", gin.H{"username": "new.hire", "password": "initial password"}, "")
+	if body := decode(t, w); w.Code != http.StatusOK || body["first_login"] != true {
+		t.Fatalf("first login: %d %v", w.Code, body)
+	}
+	token, _ := decode(t, w)["token"].(string)
+
+	w = s.get("/api/users", owner)
+	if users, _ := decode(t, w)["users"].([]any); w.Code != http.StatusOK || len(users) != 4 {
+		t.Errorf("list users: %d, %d users, want 4", w.Code, len(users))
+	}
+
+	w = s.sendJSON(http.MethodPut, path, gin.H{"role": models.RoleViewer}, owner)
+	if body := decode(t, w); w.Code != http.StatusOK || body["role"] != models.RoleViewer {
+		t.Errorf("change role: %d %v", w.Code, body)
+	}
+
+	// A password reset ends the user's sessions
+	if w := s.sendJSON(http.MethodPut, path, gin.H{"password": "reset password"}, owner); w.Code != http.StatusOK {
+		t.Fatalf("reset password: %d %s", w.Code, w.Body)
+	}
+	if w := s.get("/api/verify", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("old session after reset: %d, want 401", w.Code)
+	}
+	if w := s.postJSON("/api/login", gin.H{"username": "new.hire", "password": "reset password"}, ""); w.Code != http.StatusOK {
+		t.Errorf("login with the reset password: %d %s", w.Code, w.Body)
+	}
+
+	if w := s.do(httptest.NewRequest(http.MethodDelete, path, nil), owner); w.Code != http.StatusOK {
+		t.Fatalf("delete user: %d %s", w.Code, w.Body)
+	}
+	if w := s.postJSON("/api/login", gin.H{"username": "new.hire", "password": "reset password"}, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("login after delete: %d, want 401", w.Code)
+	}
+	if w := s.do(httptest.NewRequest(http.MethodDelete, path, nil), owner); w.Code != http.StatusNotFound {
+		t.Errorf("second delete: %d, want 404", w.Code)
+	}
+}
+
+func TestUserManagementRejectsInvalidChanges(t *testing.T) {
+	s := newDBTestServer(t, newTestConfig())
+	owner := s.login(t, models.RoleOwner)
+
+	for _, user := range []gin.H{
+		{"username": "x", "password": "long enough", "role": models.RoleViewer},
+		{"username": "valid.name", "password": "long enough", "role": "admin"},
+		{"username": "valid.name", "password": "short", "role": models.RoleViewer},
+	} {
+		if w := s.postJSON("/api/users", user, owner); w.Code != http.StatusBadRequest {
+			t.Errorf("create %v: %d, want 400", user, w.Code)
+		}
+	}
+
+	self, err := s.users.GetUser(context.Background(), models.RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/users/%d", self.ID)
+
+	if w := s.sendJSON(http.MethodPut, path, gin.H{}, owner); w.Code != http.StatusBadRequest {
+		t.Errorf("empty update: %d, want 400", w.Code)
+	}
+	if w := s.sendJSON(http.MethodPut, path, gin.H{"role": models.RoleEditor}, owner); w.Code != http.StatusConflict {
+		t.Errorf("demoting the last owner: %d, want 409", w.Code)
+	}
+	if w := s.do(httptest.NewRequest(http.MethodDelete, path, nil), owner); w.Code != http.StatusBadRequest {
+		t.Errorf("deleting yourself: %d, want 400", w.Code)
+	}
+	if w := s.sendJSON(http.MethodPut, "/api/users/999", gin.H{"role": models.RoleViewer}, owner); w.Code != http.StatusNotFound {
+		t.Errorf("updating a missing user: %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"cv-backend/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// versionIDs lists the IDs of the stored versions, newest first
+func (s *testServer) versionIDs(t *testing.T, token string) []uint {
+	t.Helper()
+	w := s.get("/api/cv/versions", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list versions: %d %s", w.Code, w.Body)
+	}
+	versions, _ := decode(t, w)["versions"].([]any)
+	ids := make([]uint, 0, len(versions))
+	for _, version := range versions {
+		id, _ := version.(map[string]any)["id"].(float64)
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// currentCV downloads the current CV
+func (s *testServer) currentCV(t *testing.T) []byte {
+	t.Helper()
+	w := s.get("/api/download-cv", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("download: %d %s", w.Code, w.Body)
+	}
+	return w.Body.Bytes()
+}
+
+func TestVersions(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+	viewer := s.login(t, models.RoleViewer)
+
+	first, second := testPDF("Version one"), testPDF("Version two")
+	for _, pdf := range [][]byte{first, second} {
+		if w := s.upload(editor, pdf, nil); w.Code != http.StatusOK {
+			t.Fatalf("upload: %d %s", w.Code, w.Body)
+		}
+	}
+
+	ids := s.versionIDs(t, viewer)
+	if len(ids) != 2 {
+		t.Fatalf("listed %d versions, want 2", len(ids))
+	}
+	newest, oldest := ids[0], ids[1]
+
+	w := s.get(fmt.Sprintf("/api/cv/versions/%d/download", oldest), viewer)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), first) {
+		t.Errorf("download of the first version: %d, content matches %v", w.Code, bytes.Equal(w.Body.Bytes(), first))
+	}
+
+	if w := s.postJSON("/api/cv/rollback", nil, editor); w.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", w.Code, w.Body)
+	}
+	if !bytes.Equal(s.currentCV(t), first) {
+		t.Error("rollback did not make the first version current")
+	}
+
+	if w := s.postJSON(fmt.Sprintf("/api/cv/versions/%d/restore", newest), nil, editor); w.Code != http.StatusOK {
+		t.Fatalf("restore: %d %s", w.Code, w.Body)
+	}
+	if !bytes.Equal(s.currentCV(t), second) {
+		t.Error("restore did not make the second version current")
+	}
+
+	// Only the current version counts, and both checks above were downloads
+	w = s.get("/api/cv-stats", viewer)
+	if body := decode(t, w); w.Code != http.StatusOK || body["fileCount"] != float64(1) || body["downloads"] != float64(2) {
+		t.Errorf("stats: %d %v, want 1 file and 2 downloads", w.Code, body)
+	}
+}
+
+func TestVersionNotFound(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	if w := s.get("/api/cv/versions/42/download", editor); w.Code != http.StatusNotFound {
+		t.Errorf("download of a missing version: %d, want 404", w.Code)
+	}
+	if w := s.postJSON("/api/cv/versions/42/restore", nil, editor); w.Code != http.StatusNotFound {
+		t.Errorf("restore of a missing version: %d, want 404", w.Code)
+	}
+	if w := s.get("/api/cv/versions/abc/download", editor); w.Code != http.StatusBadRequest {
+		t.Errorf("download with an invalid ID: %d, want 400", w.Code)
+	}
+	if w := s.postJSON("/api/cv/rollback", nil, editor); w.Code != http.StatusNotFound {
+		t.Errorf("rollback without a previous version: %d, want 404", w.Code)
+	}
+}
+
+func TestDeleteAndRestoreCV(t *testing.T) {
+	s := newTestServer(t, newTestConfig())
+	editor := s.login(t, models.RoleEditor)
+
+	pdf := testPDF("Deleted version")
+	if w := s.upload(editor, pdf, nil); w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+	ids := s.versionIDs(t, editor)
+
+	if w := s.do(httptest.NewRequest(http.MethodDelete, "/api/cv", nil), editor); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+	if w := s.get("/api/download-cv", ""); w.Code != http.StatusNotFound {
+		t.Errorf("download after delete: %d, want 404", w.Code)
+	}
+
+	// A soft-deleted version can be restored
+	if w := s.postJSON(fmt.Sprintf("/api/cv/versions/%d/restore", ids[0]), nil, editor); w.Code != http.StatusOK {
+		t.Fatalf("restore: %d %s", w.Code, w.Body)
+	}
+	if !bytes.Equal(s.currentCV(t), pdf) {
+		t.Error("restored CV differs from the upload")
+	}
+
+	if w := s.do(httptest.NewRequest(http.MethodDelete, "/api/cv?permanent=true", nil), editor); w.Code != http.StatusOK {
+		t.Fatalf("permanent delete: %d %s", w.Code, w.Body)
+	}
+	if w := s.postJSON(fmt.Sprintf("/api/cv/versions/%d/restore", ids[0]), nil, editor); w.Code != http.StatusNotFound {
+		t.Errorf("restore after permanent delete: %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Access event kinds
+const (
+	AccessDownload = "download"
+	AccessView     = "view"
+)
+
+// UserAgentBot is the user agent class of crawlers and other automated clients
+const UserAgentBot = "bot"
+
+// AnalyticsBucket aggregates the access events of one day, week or month
+type AnalyticsBucket struct {
+	Period         string `json:"period"` // 2006-01-02 for days and weeks (week starts Monday), 2006-01 for months
+	Views          int    `json:"views"`
+	Downloads      int    `json:"downloads"`
+	UniqueVisitors int    `json:"uniqueVisitors"`
+}
+
+// ReferrerCount is the number of accesses coming from one referring host
+type ReferrerCount struct {
+	Referrer string `gorm:"column:referrer" json:"referrer"`
+	Count    int    `gorm:"column:count" json:"count"`
+}
+
+// VersionAnalytics aggregates the access events of one CV version
+type VersionAnalytics struct {
+	CVFileID     uint      `gorm:"column:cv_file_id" json:"versionId"`
+	OriginalName string    `gorm:"column:original_name" json:"originalName"`
+	Language     string    `gorm:"column:language" json:"language"`
+	UploadedAt   time.Time `gorm:"column:created_at" json:"uploadedAt"`
+	Views        int       `gorm:"column:views" json:"views"`
+	Downloads    int       `gorm:"column:downloads" json:"downloads"`
+}
+
+// AnalyticsStorage records and aggregates CV downloads and views
+type AnalyticsStorage struct {
+	db *gorm.DB
+}
+
+// NewAnalyticsStorage creates a new AnalyticsStorage instance
+func NewAnalyticsStorage(db *gorm.DB) *AnalyticsStorage {
+	return &AnalyticsStorage{
+		db: db,
+	}
+}
+
+// RecordAccess stores a download or view event
+func (as *AnalyticsStorage) RecordAccess(ctx context.Context, event *models.CVAccessEvent) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = utcNow()
+	}
+	event.OccurredAt = event.OccurredAt.UTC()
+	if err := as.db.WithContext(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record CV access: %w", err)
+	}
+	return nil
+}
+
+// eventsBetween limits a query to events in [from, to), optionally without bots
+func eventsBetween(from, to time.Time, includeBots bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("cv_access_events.occurred_at >= ? AND cv_access_events.occurred_at < ?", from.UTC(), to.UTC())
+		if !includeBots {
+			db = db.Where("cv_access_events.user_agent_class <> ?", UserAgentBot)
+		}
+		return db
+	}
+}
+
+// Timeseries aggregates events in [from, to) per day, week or month.
+// Buckets are computed in UTC; periods without events are included with zero counts.
+func (as *AnalyticsStorage) Timeseries(ctx context.Context, from, to time.Time, granularity string, includeBots bool) ([]AnalyticsBucket, error) {
+	var events []models.CVAccessEvent
+	if err := as.db.WithContext(ctx).Select("kind", "occurred_at", "ip_hash").
+		Scopes(eventsBetween(from, to, includeBots)).
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to load access events: %w", err)
+	}
+
+	// Bucketing happens here rather than in SQL so it behaves the same on every database
+	buckets := make(map[string]*AnalyticsBucket)
+	visitors := make(map[string]map[string]bool)
+	for start := bucketStart(from, granularity); start.Before(to); start = nextBucket(start, granularity) {
+		period := formatBucket(start, granularity)
+		buckets[period] = &AnalyticsBucket{Period: period}
+		visitors[period] = make(map[string]bool)
+	}
+
+	for _, event := range events {
+		period := formatBucket(bucketStart(event.OccurredAt, granularity), granularity)
+		bucket, ok := buckets[period]
+		if !ok {
+			continue
+		}
+		switch event.Kind {
+		case AccessDownload:
+			bucket.Downloads++
+		case AccessView:
+			bucket.Views++
+		}
+		if event.IPHash != "" {
+			visitors[period][event.IPHash] = true
+		}
+	}
+
+	result := make([]AnalyticsBucket, 0, len(buckets))
+	for period, bucket := range buckets {
+		bucket.UniqueVisitors = len(visitors[period])
+		result = append(result, *bucket)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Period < result[j].Period })
+
+	return result, nil
+}
+
+// UniqueVisitors counts distinct visitors in [from, to)
+func (as *AnalyticsStorage) UniqueVisitors(ctx context.Context, from, to time.Time, includeBots bool) (int, error) {
+	var count int64
+	if err := as.db.WithContext(ctx).Model(&models.CVAccessEvent{}).
+		Scopes(eventsBetween(from, to, includeBots)).
+		Where("ip_hash <> ''").
+		Distinct("ip_hash").
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count unique visitors: %w", err)
+	}
+	return int(count), nil
+}
+
+// TopReferrers returns the referring hosts with the most accesses in [from, to)
+func (as *AnalyticsStorage) TopReferrers(ctx context.Context, from, to time.Time, limit int, includeBots bool) ([]ReferrerCount, error) {
+	var referrers []ReferrerCount
+	if err := as.db.WithContext(ctx).Model(&models.CVAccessEvent{}).
+		Select("referrer, COUNT(*) AS count").
+		Scopes(eventsBetween(from, to, includeBots)).
+		Where("referrer <> ''").
+		Group("referrer").
+		Order("count DESC, referrer").
+		Limit(limit).
+		Scan(&referrers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get top referrers: %w", err)
+	}
+	return referrers, nil
+}
+
+// VersionBreakdown returns views and downloads per CV version in [from, to)
+func (as *AnalyticsStorage) VersionBreakdown(ctx context.Context, from, to time.Time, includeBots bool) ([]VersionAnalytics, error) {
+	var versions []VersionAnalytics
+	if err := as.db.WithContext(ctx).Model(&models.CVAccessEvent{}).
+		Select(`cv_access_events.cv_file_id, cv_files.original_name, cv_files.language, cv_files.created_at,
+			SUM(CASE WHEN cv_access_events.kind = ? THEN 1 ELSE 0 END) AS views,
+			SUM(CASE WHEN cv_access_events.kind = ? THEN 1 ELSE 0 END) AS downloads`, AccessView, AccessDownload).
+		Joins("JOIN cv_files ON cv_files.id = cv_access_events.cv_file_id").
+		Scopes(eventsBetween(from, to, includeBots)).
+		Group("cv_access_events.cv_file_id, cv_files.original_name, cv_files.language, cv_files.created_at").
+		Order("cv_files.created_at DESC").
+		Scan(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get version breakdown: %w", err)
+	}
+	return versions, nil
+}
+
+// AccessTotals returns the number of downloads and views of all time, without bots
+func (as *AnalyticsStorage) AccessTotals(ctx context.Context) (downloads int, views int, err error) {
+	var totals struct {
+		Downloads int `gorm:"column:downloads"`
+		Views     int `gorm:"column:views"`
+	}
+	err = as.db.WithContext(ctx).Model(&models.CVAccessEvent{}).
+		Select(`COALESCE(SUM(CASE WHEN kind = ? THEN 1 ELSE 0 END), 0) AS downloads,
+			COALESCE(SUM(CASE WHEN kind = ? THEN 1 ELSE 0 END), 0) AS views`, AccessDownload, AccessView).
+		Where("user_agent_class <> ?", UserAgentBot).
+		Scan(&totals).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get access totals: %w", err)
+	}
+	return totals.Downloads, totals.Views, nil
+}
+
+// bucketStart truncates t to the start of its day, ISO week or month in UTC
+func bucketStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -offset)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func nextBucket(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func formatBucket(start time.Time, granularity string) string {
+	if granularity == "month" {
+		return start.Format("2006-01")
+	}
+	return start.Format("2006-01-02")
+}
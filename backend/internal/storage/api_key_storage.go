@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/auth"
+	"cv-backend/internal/models"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when no API key matches an ID
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKey is returned for unknown, expired or revoked API keys
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// APIKeyStorage handles the API keys of users
+type APIKeyStorage struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyStorage creates a new APIKeyStorage instance
+func NewAPIKeyStorage(db *gorm.DB) *APIKeyStorage {
+	return &APIKeyStorage{
+		db: db,
+	}
+}
+
+// CreateAPIKey stores a new API key for a user and returns it together with
+// the key itself, which cannot be recovered later
+func (as *APIKeyStorage) CreateAPIKey(ctx context.Context, userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey := models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   auth.HashAPIKey(key),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: utcTime(expiresAt),
+	}
+	if err := as.db.WithContext(ctx).Create(&apiKey).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &apiKey, key, nil
+}
+
+// ListAPIKeys returns the API keys of a user, newest first
+func (as *APIKeyStorage) ListAPIKeys(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := as.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// GetAPIKey retrieves an API key by ID
+func (as *APIKeyStorage) GetAPIKey(ctx context.Context, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := as.db.WithContext(ctx).First(&key, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey disables an API key; revoking it again keeps the first revocation time
+func (as *APIKeyStorage) RevokeAPIKey(ctx context.Context, id uint) (*models.APIKey, error) {
+	result := as.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", utcNow())
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	return as.GetAPIKey(ctx, id)
+}
+
+// Authenticate looks up an active API key and its user and records its use
+func (as *APIKeyStorage) Authenticate(ctx context.Context, key string) (*models.APIKey, *models.User, error) {
+	var apiKey models.APIKey
+	if err := as.db.WithContext(ctx).Where("key_hash = ?", auth.HashAPIKey(key)).First(&apiKey).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	now := utcNow()
+	if !apiKey.Active(now) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var user models.User
+	if err := as.db.WithContext(ctx).First(&user, apiKey.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := as.db.WithContext(ctx).Model(&apiKey).Update("last_used_at", now).Error; err != nil {
+		slog.WarnContext(ctx, "failed to update API key usage", "error", err)
+	}
+
+	return &apiKey, &user, nil
+}
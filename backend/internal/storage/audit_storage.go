@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Audit event actions
+const (
+	AuditAccountLocked   = "account_locked"
+	AuditAccountUnlocked = "account_unlocked"
+	AuditIPBlocked       = "ip_blocked"
+	AuditIPUnblocked     = "ip_unblocked"
+	AuditUserCreated     = "user_created"
+	AuditUserUpdated     = "user_updated"
+	AuditUserDeleted     = "user_deleted"
+	AuditAPIKeyCreated   = "api_key_created"
+	AuditAPIKeyRevoked   = "api_key_revoked"
+	AuditCVQuarantined   = "cv_quarantined"
+)
+
+// AuditStorage handles the audit trail of security-relevant events
+type AuditStorage struct {
+	db *gorm.DB
+}
+
+// NewAuditStorage creates a new AuditStorage instance
+func NewAuditStorage(db *gorm.DB) *AuditStorage {
+	return &AuditStorage{
+		db: db,
+	}
+}
+
+// Record appends an event to the audit trail
+func (as *AuditStorage) Record(ctx context.Context, event *models.AuditEvent) error {
+	if err := as.db.WithContext(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// ListEvents returns the most recent audit events, newest first
+func (as *AuditStorage) ListEvents(ctx context.Context, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	if err := as.db.WithContext(ctx).Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, nil
+}
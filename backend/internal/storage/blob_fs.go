@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FSBlobStore stores blobs as files below a root directory
+type FSBlobStore struct {
+	root string
+}
+
+// NewFSBlobStore creates a filesystem blob store rooted at dir
+func NewFSBlobStore(dir string) (*FSBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &FSBlobStore{root: dir}, nil
+}
+
+func (fs *FSBlobStore) path(key string) (string, error) {
+	if err := validateBlobKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(fs.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the blob to a temporary file and renames it into place
+func (fs *FSBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if size >= 0 && written != size {
+		tmp.Close()
+		return fmt.Errorf("blob size mismatch: expected %d bytes, got %d", size, written)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close blob: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	return nil
+}
+
+// Get opens the blob file for reading
+func (fs *FSBlobStore) Get(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := fs.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return f, nil
+}
+
+// Delete removes the blob file
+func (fs *FSBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that the root directory still exists
+func (fs *FSBlobStore) Ping(ctx context.Context) error {
+	info, err := os.Stat(fs.root)
+	if err != nil {
+		return fmt.Errorf("failed to stat blob directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("blob directory %s is not a directory", fs.root)
+	}
+	return nil
+}
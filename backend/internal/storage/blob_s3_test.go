@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a stand-in for an S3 bucket served with path-style addressing
+type fakeS3 struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+	fetches []string // HEAD and GET requests for objects, with their Range header
+}
+
+var authorizationPattern = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=test-key/\d{8}/eu-central-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}$`)
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorizationPattern.MatchString(r.Header.Get("Authorization")) || r.Header.Get("X-Amz-Date") == "" {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	prefix := "/" + f.bucket
+	if r.URL.Path == prefix && r.Method == http.MethodHead {
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, prefix+"/") {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix+"/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = data
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		f.fetches = append(f.fetches, strings.TrimSpace(r.Method+" "+r.Header.Get("Range")))
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(data))
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3(t *testing.T) (*S3BlobStore, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{bucket: "cv-bucket", objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3BlobStore(S3Config{
+		Endpoint:        server.URL,
+		Region:          "eu-central-1",
+		Bucket:          "cv-bucket",
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, fake
+}
+
+func TestS3BlobStore(t *testing.T) {
+	store, fake := newTestS3(t)
+	ctx := context.Background()
+	content := []byte("0123456789abcdefghij")
+
+	if err := store.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	if err := store.Put(ctx, "cv/blob", bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if !bytes.Equal(fake.objects["cv/blob"], content) {
+		t.Fatalf("stored %q, want %q", fake.objects["cv/blob"], content)
+	}
+
+	blob, err := store.Get(ctx, "cv/blob")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer blob.Close()
+
+	// Seeking to the end for the size, as http.ServeContent does, fetches nothing
+	if size, err := blob.Seek(0, io.SeekEnd); err != nil || size != int64(len(content)) {
+		t.Fatalf("Seek() = %d, %v, want %d", size, err, len(content))
+	}
+	if _, err := blob.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"HEAD"}; strings.Join(fake.fetches, ",") != strings.Join(want, ",") {
+		t.Errorf("requests after Get() = %q, want %q", fake.fetches, want)
+	}
+
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(blob, head); err != nil || string(head) != "01234" {
+		t.Fatalf("read %q, %v, want %q", head, err, "01234")
+	}
+
+	// Seeking away from the read position continues with a ranged GET
+	if pos, err := blob.Seek(-5, io.SeekEnd); err != nil || pos != 15 {
+		t.Fatalf("Seek() = %d, %v, want 15", pos, err)
+	}
+	rest, err := io.ReadAll(blob)
+	if err != nil || string(rest) != "fghij" {
+		t.Fatalf("read %q, %v, want %q", rest, err, "fghij")
+	}
+	if want := []string{"HEAD", "GET", "GET bytes=15-"}; strings.Join(fake.fetches, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %q, want %q", fake.fetches, want)
+	}
+
+	if err := store.Delete(ctx, "cv/blob"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "cv/blob"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrBlobNotFound", err)
+	}
+	// Deleting a missing object is not an error
+	if err := store.Delete(ctx, "cv/blob"); err != nil {
+		t.Errorf("Delete() of missing blob error = %v", err)
+	}
+}
+
+func TestS3BlobStoreReportsErrors(t *testing.T) {
+	store, _ := newTestS3(t)
+	store.cfg.Bucket = "other-bucket"
+
+	err := store.Put(context.Background(), "blob", strings.NewReader("data"), 4, "")
+	if err == nil || !strings.Contains(err.Error(), "NoSuchBucket") {
+		t.Errorf("Put() error = %v, want NoSuchBucket", err)
+	}
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("Ping() of a missing bucket succeeded")
+	}
+}
+
+func TestS3ObjectURL(t *testing.T) {
+	store, err := NewS3BlobStore(S3Config{
+		Endpoint:        "https://s3.example.com/",
+		Bucket:          "cv",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := store.objectURL("a b/c+d").String(); got != "https://cv.s3.example.com/a%20b/c%2Bd" {
+		t.Errorf("virtual-hosted URL = %q", got)
+	}
+	store.cfg.PathStyle = true
+	if got := store.objectURL("key").String(); got != "https://s3.example.com/cv/key" {
+		t.Errorf("path-style URL = %q", got)
+	}
+}
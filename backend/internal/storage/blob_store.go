@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/config"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ErrBlobNotFound is returned when a blob does not exist in the store
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores the binary content of CV files outside the database
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key for reading. The returned reader is
+	// seekable so that downloads can serve byte ranges without buffering.
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the blob stored under key; missing blobs are not an error
+	Delete(ctx context.Context, key string) error
+	// Ping checks that the store is reachable, for the readiness probe
+	Ping(ctx context.Context) error
+}
+
+var Blobs BlobStore
+
+// InitBlobStore initializes the blob store selected by cfg.Blob.Backend (BLOB_BACKEND)
+func InitBlobStore(cfg *config.Config) error {
+	switch cfg.Blob.Backend {
+	case "fs", "filesystem":
+		store, err := NewFSBlobStore(cfg.Blob.Dir)
+		if err != nil {
+			return err
+		}
+		Blobs = store
+		slog.Info("blob storage ready", "backend", "fs", "dir", cfg.Blob.Dir)
+	case "s3":
+		store, err := NewS3BlobStore(S3Config{
+			Endpoint:        cfg.Blob.S3Endpoint,
+			Region:          cfg.Blob.S3Region,
+			Bucket:          cfg.Blob.S3Bucket,
+			AccessKeyID:     cfg.Blob.S3AccessKeyID,
+			SecretAccessKey: cfg.Blob.S3SecretAccessKey,
+			PathStyle:       cfg.Blob.S3PathStyle,
+		})
+		if err != nil {
+			return err
+		}
+		Blobs = store
+		slog.Info("blob storage ready", "backend", "s3", "bucket", cfg.Blob.S3Bucket)
+	default:
+		return fmt.Errorf("unknown BLOB_BACKEND %q (expected fs or s3)", cfg.Blob.Backend)
+	}
+
+	return nil
+}
+
+// GetBlobStore returns the configured blob store
+func GetBlobStore() BlobStore {
+	return Blobs
+}
+
+// validateBlobKey rejects keys that could escape the store's namespace
+func validateBlobKey(key string) error {
+	if key == "" {
+		return errors.New("blob key must not be empty")
+	}
+	if strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
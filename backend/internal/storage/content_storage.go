@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownContentSection is returned for a section name that does not exist
+var ErrUnknownContentSection = errors.New("unknown content section")
+
+// ErrContentEntryNotFound is returned when a content entry does not exist
+var ErrContentEntryNotFound = errors.New("content entry not found")
+
+// contentSections maps the section names used in the API to their models
+var contentSections = map[string]models.ContentItem{
+	"experiences":  &models.Experience{},
+	"education":    &models.Education{},
+	"skills":       &models.SkillCategory{},
+	"industries":   &models.Industry{},
+	"languages":    &models.SpokenLanguage{},
+	"hobbies":      &models.Hobby{},
+	"achievements": &models.Achievement{},
+}
+
+// ContentSections returns the names of all content sections
+func ContentSections() []string {
+	return []string{"experiences", "education", "skills", "industries", "languages", "hobbies", "achievements"}
+}
+
+// ContentStorage handles the structured CV content sections
+type ContentStorage struct {
+	db *gorm.DB
+}
+
+// NewContentStorage creates a new ContentStorage instance
+func NewContentStorage(db *gorm.DB) *ContentStorage {
+	return &ContentStorage{
+		db: db,
+	}
+}
+
+// NewEntry returns a new, visible entry of the section's model
+func (cs *ContentStorage) NewEntry(section string) (models.ContentItem, error) {
+	model, ok := contentSections[section]
+	if !ok {
+		return nil, ErrUnknownContentSection
+	}
+
+	entry := reflect.New(reflect.TypeOf(model).Elem()).Interface().(models.ContentItem)
+	entry.Entry().Visible = true
+	return entry, nil
+}
+
+// ListEntries returns the entries of a section ordered by position.
+// Hidden entries are only included if includeHidden is set.
+func (cs *ContentStorage) ListEntries(ctx context.Context, section string, includeHidden bool) (interface{}, error) {
+	model, ok := contentSections[section]
+	if !ok {
+		return nil, ErrUnknownContentSection
+	}
+
+	list := reflect.New(reflect.SliceOf(reflect.TypeOf(model).Elem()))
+	query := cs.db.WithContext(ctx).Order("position, id")
+	if !includeHidden {
+		query = query.Where("visible = ?", true)
+	}
+	if err := query.Find(list.Interface()).Error; err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", section, err)
+	}
+
+	return list.Elem().Interface(), nil
+}
+
+// GetEntry returns a single entry of a section
+func (cs *ContentStorage) GetEntry(ctx context.Context, section string, id uint) (models.ContentItem, error) {
+	entry, err := cs.NewEntry(section)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.db.WithContext(ctx).First(entry, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrContentEntryNotFound
+		}
+		return nil, fmt.Errorf("failed to get %s entry: %w", section, err)
+	}
+
+	return entry, nil
+}
+
+// CreateEntry inserts a new entry created with NewEntry
+func (cs *ContentStorage) CreateEntry(ctx context.Context, entry models.ContentItem) error {
+	if err := cs.db.WithContext(ctx).Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create content entry: %w", err)
+	}
+	return nil
+}
+
+// SaveEntry writes all fields of an existing entry
+func (cs *ContentStorage) SaveEntry(ctx context.Context, entry models.ContentItem) error {
+	if err := cs.db.WithContext(ctx).Save(entry).Error; err != nil {
+		return fmt.Errorf("failed to save content entry: %w", err)
+	}
+	return nil
+}
+
+// DeleteEntry deletes an entry of a section
+func (cs *ContentStorage) DeleteEntry(ctx context.Context, section string, id uint) error {
+	entry, err := cs.NewEntry(section)
+	if err != nil {
+		return err
+	}
+
+	result := cs.db.WithContext(ctx).Delete(entry, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete %s entry: %w", section, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrContentEntryNotFound
+	}
+
+	return nil
+}
+
+// ReorderEntries sets the position of each entry to its index in ids
+func (cs *ContentStorage) ReorderEntries(ctx context.Context, section string, ids []uint) error {
+	entry, err := cs.NewEntry(section)
+	if err != nil {
+		return err
+	}
+
+	return cs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			result := tx.Model(entry).Where("id = ?", id).Update("position", position)
+			if result.Error != nil {
+				return fmt.Errorf("failed to reorder %s: %w", section, result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return ErrContentEntryNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceSection deletes all entries of a section and inserts the given ones
+// in order. IDs of the new entries are assigned by the database.
+func (cs *ContentStorage) ReplaceSection(ctx context.Context, section string, entries []models.ContentItem) error {
+	model, err := cs.NewEntry(section)
+	if err != nil {
+		return err
+	}
+
+	return cs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(model).Error; err != nil {
+			return fmt.Errorf("failed to clear %s: %w", section, err)
+		}
+
+		for position, entry := range entries {
+			entry.Entry().ID = 0
+			entry.Entry().Position = position
+			if err := tx.Create(entry).Error; err != nil {
+				return fmt.Errorf("failed to import %s: %w", section, err)
+			}
+		}
+		return nil
+	})
+}
+
+// PublicContent returns the visible entries of every section
+func (cs *ContentStorage) PublicContent(ctx context.Context) (*models.CVContent, error) {
+	content := &models.CVContent{}
+	sections := map[string]interface{}{
+		"experiences":  &content.Experiences,
+		"education":    &content.Education,
+		"skills":       &content.Skills,
+		"industries":   &content.Industries,
+		"languages":    &content.Languages,
+		"hobbies":      &content.Hobbies,
+		"achievements": &content.Achievements,
+	}
+
+	for _, section := range ContentSections() {
+		if err := cs.db.WithContext(ctx).Where("visible = ?", true).Order("position, id").Find(sections[section]).Error; err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", section, err)
+		}
+	}
+	return content, nil
+}
@@ -1,57 +1,122 @@
 package storage
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"cv-backend/internal/models"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"time"
+	"log/slog"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// CVStorage handles CV file operations in the database
+// ErrCVVersionNotFound is returned when a requested CV version does not exist
+var ErrCVVersionNotFound = errors.New("CV version not found")
+
+// ErrCVVersionQuarantined is returned when a quarantined version is opened or restored
+var ErrCVVersionQuarantined = errors.New("CV version is quarantined")
+
+// CVStorage handles CV file metadata in the database and file content in the blob store
 type CVStorage struct {
-	db *gorm.DB
+	db    *gorm.DB
+	blobs BlobStore
 }
 
 // NewCVStorage creates a new CVStorage instance
-func NewCVStorage() *CVStorage {
+func NewCVStorage(db *gorm.DB, blobs BlobStore) *CVStorage {
 	return &CVStorage{
-		db: GetDB(),
+		db:    db,
+		blobs: blobs,
 	}
 }
 
-// UploadCV saves a CV file to database (replaces existing CV)
-func (cs *CVStorage) UploadCV(file io.Reader, originalName string, fileSize int64, contentType string) (*models.CVFile, error) {
-	// Read file data
-	fileData, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file data: %w", err)
+// newStorageKey generates a unique blob key for a new CV file
+func newStorageKey() (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate storage key: %w", err)
 	}
+	return fmt.Sprintf("cv/%s-%s.pdf", utcNow().Format("20060102T150405"), hex.EncodeToString(suffix)), nil
+}
 
+// UploadCV streams a CV file to the blob store and records it as the current CV
+// for its language. Older versions are kept as non-current rows. A quarantined
+// file is recorded without becoming current. If another version has the same
+// SHA-256, the new version shares its blob and the file is not stored again.
+func (cs *CVStorage) UploadCV(ctx context.Context, file io.Reader, originalName string, fileSize int64, digest, contentType, language, changeNote string, scan models.ScanResult) (*models.CVFile, error) {
 	// Start transaction
-	tx := cs.db.Begin()
+	tx := cs.db.WithContext(ctx).Begin()
 	if tx.Error != nil {
 		return nil, fmt.Errorf("failed to start transaction: %w", tx.Error)
 	}
 	defer tx.Rollback()
 
-	// Mark all existing files as not current
-	if err := tx.Model(&models.CVFile{}).Where("is_current = ?", true).Updates(map[string]interface{}{
-		"is_current": false,
-		"updated_at": time.Now(),
-	}).Error; err != nil {
-		return nil, fmt.Errorf("failed to update existing files: %w", err)
+	// Mark existing files of the same language as not current
+	quarantined := scan.Status == models.ScanStatusQuarantined
+	if !quarantined {
+		if err := tx.Model(&models.CVFile{}).Where("is_current = ? AND language = ?", true, language).Updates(map[string]interface{}{
+			"is_current": false,
+			"updated_at": utcNow(),
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update existing files: %w", err)
+		}
+	}
+
+	// Reuse the blob of any version with the same content, deleted ones
+	// included. Quarantined files keep blobs of their own, so a clean version
+	// never points at a flagged blob. The row lock keeps a permanent delete
+	// from removing the blob before this version references it.
+	var storageKey string
+	if !quarantined {
+		var duplicate models.CVFile
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("storage_key").
+			Where("content_sha256 = ? AND storage_key <> '' AND scan_status <> ?", digest, models.ScanStatusQuarantined).
+			Order("id").Limit(1).Find(&duplicate).Error; err != nil {
+			return nil, fmt.Errorf("failed to look up duplicate content: %w", err)
+		}
+		storageKey = duplicate.StorageKey
+	}
+
+	// Only store the file if no version has its content yet
+	committed := false
+	if storageKey == "" {
+		uploadedKey, err := newStorageKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := cs.blobs.Put(ctx, uploadedKey, file, fileSize, contentType); err != nil {
+			return nil, fmt.Errorf("failed to store file data: %w", err)
+		}
+		storageKey = uploadedKey
+
+		// Remove the blob again if the metadata cannot be recorded, even if
+		// the request has been cancelled
+		defer func() {
+			if !committed {
+				cs.blobs.Delete(context.WithoutCancel(ctx), uploadedKey)
+			}
+		}()
 	}
 
 	// Insert new CV file
 	cvFile := models.CVFile{
-		FileName:     "current_cv.pdf",
-		OriginalName: originalName,
-		ContentType:  contentType,
-		FileSize:     fileSize,
-		FileData:     fileData,
-		IsCurrent:    true,
+		FileName:      "current_cv.pdf",
+		OriginalName:  originalName,
+		ContentType:   contentType,
+		FileSize:      fileSize,
+		Language:      language,
+		StorageKey:    storageKey,
+		SHA256:        digest,
+		ChangeNote:    changeNote,
+		ScanStatus:    scan.Status,
+		ScanSignature: scan.Signature,
+		IsCurrent:     !quarantined,
 	}
 
 	if err := tx.Create(&cvFile).Error; err != nil {
@@ -62,14 +127,15 @@ func (cs *CVStorage) UploadCV(file io.Reader, originalName string, fileSize int6
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	committed = true
 
 	return &cvFile, nil
 }
 
-// GetCurrentCV returns the current CV file metadata
-func (cs *CVStorage) GetCurrentCV() (*models.CVFile, error) {
+// GetCurrentCV returns the current CV file metadata for a language
+func (cs *CVStorage) GetCurrentCV(ctx context.Context, language string) (*models.CVFile, error) {
 	var cvFile models.CVFile
-	err := cs.db.Where("is_current = ?", true).
+	err := cs.db.WithContext(ctx).Where("is_current = ? AND language = ?", true, language).
 		Order("created_at DESC").
 		First(&cvFile).Error
 
@@ -83,47 +149,291 @@ func (cs *CVStorage) GetCurrentCV() (*models.CVFile, error) {
 	return &cvFile, nil
 }
 
-// GetCurrentCVWithData returns the current CV with file data
-func (cs *CVStorage) GetCurrentCVWithData() (*models.CVFile, []byte, error) {
+// CurrentLanguages returns the languages that have a current CV
+func (cs *CVStorage) CurrentLanguages(ctx context.Context) ([]string, error) {
+	var languages []string
+	if err := cs.db.WithContext(ctx).Model(&models.CVFile{}).
+		Where("is_current = ?", true).
+		Distinct().
+		Order("language").
+		Pluck("language", &languages).Error; err != nil {
+		return nil, fmt.Errorf("failed to list CV languages: %w", err)
+	}
+
+	return languages, nil
+}
+
+// OpenCurrentCV returns the current CV metadata for a language and a reader
+// for its content. The caller must close the reader.
+func (cs *CVStorage) OpenCurrentCV(ctx context.Context, language string) (*models.CVFile, io.ReadSeekCloser, error) {
+	cvFile, err := cs.GetCurrentCV(ctx, language)
+	if err != nil || cvFile == nil {
+		return nil, nil, err
+	}
+
+	content, err := cs.blobs.Get(ctx, cvFile.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open CV content: %w", err)
+	}
+
+	return cvFile, content, nil
+}
+
+// ListVersions returns all CV versions, newest first, including soft-deleted ones
+func (cs *CVStorage) ListVersions(ctx context.Context) ([]models.CVFile, error) {
+	var versions []models.CVFile
+	if err := cs.db.WithContext(ctx).Unscoped().Order("created_at DESC").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list CV versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetVersion returns the metadata of a single CV version, including soft-deleted ones
+func (cs *CVStorage) GetVersion(ctx context.Context, id uint) (*models.CVFile, error) {
 	var cvFile models.CVFile
-	
-	err := cs.db.Where("is_current = ?", true).
-		Order("created_at DESC").
-		First(&cvFile).Error
+	if err := cs.db.WithContext(ctx).Unscoped().First(&cvFile, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCVVersionNotFound
+		}
+		return nil, fmt.Errorf("failed to get CV version: %w", err)
+	}
+
+	return &cvFile, nil
+}
+
+// OpenVersion returns a CV version and a reader for its content.
+// The caller must close the reader. Quarantined versions cannot be opened.
+func (cs *CVStorage) OpenVersion(ctx context.Context, id uint) (*models.CVFile, io.ReadSeekCloser, error) {
+	cvFile, err := cs.GetVersion(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cvFile.Quarantined() {
+		return nil, nil, ErrCVVersionQuarantined
+	}
+
+	content, err := cs.blobs.Get(ctx, cvFile.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open CV content: %w", err)
+	}
+
+	return cvFile, content, nil
+}
+
+// RestoreVersion makes the given version the current CV for its language again,
+// undeleting it if necessary. Quarantined versions cannot be restored.
+func (cs *CVStorage) RestoreVersion(ctx context.Context, id uint) (*models.CVFile, error) {
+	cvFile, err := cs.GetVersion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cvFile.Quarantined() {
+		return nil, ErrCVVersionQuarantined
+	}
+
+	err = cs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CVFile{}).Where("is_current = ? AND language = ? AND id <> ?", true, cvFile.Language, id).Updates(map[string]interface{}{
+			"is_current": false,
+			"updated_at": utcNow(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update existing files: %w", err)
+		}
 
+		return tx.Unscoped().Model(cvFile).Updates(map[string]interface{}{
+			"is_current": true,
+			"deleted_at": nil,
+			"updated_at": utcNow(),
+		}).Error
+	})
 	if err != nil {
+		return nil, fmt.Errorf("failed to restore CV version: %w", err)
+	}
+
+	return cs.GetVersion(ctx, id)
+}
+
+// RollbackCV restores the version of a language that was uploaded before the current one
+func (cs *CVStorage) RollbackCV(ctx context.Context, language string) (*models.CVFile, error) {
+	query := cs.db.WithContext(ctx).Where("is_current = ? AND language = ? AND scan_status <> ?", false, language, models.ScanStatusQuarantined)
+
+	current, err := cs.GetCurrentCV(ctx, language)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		query = query.Where("created_at < ?", current.CreatedAt)
+	}
+
+	var previous models.CVFile
+	if err := query.Order("created_at DESC").First(&previous).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, nil, nil // No CV found
+			return nil, ErrCVVersionNotFound
 		}
-		return nil, nil, fmt.Errorf("failed to get current CV with data: %w", err)
+		return nil, fmt.Errorf("failed to find previous CV version: %w", err)
 	}
 
-	return &cvFile, cvFile.FileData, nil
+	return cs.RestoreVersion(ctx, previous.ID)
 }
 
-// DeleteCV deletes the current CV file
-func (cs *CVStorage) DeleteCV() error {
-	result := cs.db.Where("is_current = ?", true).Delete(&models.CVFile{})
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete CV: %w", result.Error)
+// DeleteCV deletes the current CV of a language, or of all languages if
+// language is empty. A soft delete keeps the row and its content so the
+// version can be restored later; a permanent delete removes both, except
+// for content that other versions share.
+func (cs *CVStorage) DeleteCV(ctx context.Context, language string, permanent bool) error {
+	currentFiles := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("is_current = ?", true)
+		if language != "" {
+			db = db.Where("language = ?", language)
+		}
+		return db
+	}
+
+	var current []models.CVFile
+	if err := cs.db.WithContext(ctx).Scopes(currentFiles).Find(&current).Error; err != nil {
+		return fmt.Errorf("failed to delete CV: %w", err)
 	}
 
-	if result.RowsAffected == 0 {
+	if len(current) == 0 {
 		return fmt.Errorf("no CV found to delete")
 	}
 
+	if !permanent {
+		if err := cs.db.WithContext(ctx).Model(&models.CVFile{}).Scopes(currentFiles).Updates(map[string]interface{}{
+			"is_current": false,
+			"deleted_at": utcNow(),
+			"updated_at": utcNow(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to delete CV: %w", err)
+		}
+		return nil
+	}
+
+	// Delete the rows and decide which blobs lost their last reference in one
+	// transaction. The delete locks the rows an upload may reuse a blob
+	// through, so a concurrent upload either sees them gone or is counted here.
+	var unreferenced []string
+	err := cs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&current).Error; err != nil {
+			return err
+		}
+
+		seen := make(map[string]bool)
+		for _, cvFile := range current {
+			if seen[cvFile.StorageKey] {
+				continue
+			}
+			seen[cvFile.StorageKey] = true
+
+			var references int64
+			if err := tx.Unscoped().Model(&models.CVFile{}).Where("storage_key = ?", cvFile.StorageKey).Count(&references).Error; err != nil {
+				return fmt.Errorf("failed to count blob references: %w", err)
+			}
+			if references == 0 {
+				unreferenced = append(unreferenced, cvFile.StorageKey)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete CV: %w", err)
+	}
+
+	// The rows are gone, so the blobs are removed even if the request has
+	// been cancelled in the meantime
+	blobCtx := context.WithoutCancel(ctx)
+	for _, storageKey := range unreferenced {
+		if err := cs.blobs.Delete(blobCtx, storageKey); err != nil {
+			slog.WarnContext(ctx, "failed to delete blob", "key", storageKey, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// MigrateLegacyFileData moves CV content stored in the old file_data BYTEA
+// column into the blob store and drops the column afterwards
+func (cs *CVStorage) MigrateLegacyFileData(ctx context.Context) error {
+	if !cs.db.WithContext(ctx).Migrator().HasColumn(&models.CVFile{}, "file_data") {
+		return nil
+	}
+
+	var ids []uint
+	if err := cs.db.WithContext(ctx).Unscoped().Model(&models.CVFile{}).Where("storage_key = ''").Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to list legacy CV files: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := cs.migrateLegacyFile(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	// The SQLite migrator of GORM cannot find unquoted columns to drop, so
+	// the column is dropped with plain SQL, which both databases support
+	if err := cs.db.WithContext(ctx).Exec("ALTER TABLE cv_files DROP COLUMN file_data").Error; err != nil {
+		return fmt.Errorf("failed to drop file_data column: %w", err)
+	}
+
+	slog.InfoContext(ctx, "moved legacy CV files to blob storage", "count", len(ids))
 	return nil
 }
 
+// migrateLegacyFile moves the file_data of one row into the blob store. The
+// row is updated in a transaction, and the blob is deleted again if the
+// update fails, so a failed migration leaves no orphaned blob behind.
+func (cs *CVStorage) migrateLegacyFile(ctx context.Context, id uint) error {
+	storageKey, err := newStorageKey()
+	if err != nil {
+		return err
+	}
+
+	stored := false
+	err = cs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var legacy struct {
+			FileData    []byte `gorm:"column:file_data"`
+			ContentType string `gorm:"column:content_type"`
+		}
+		result := tx.Unscoped().Model(&models.CVFile{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("file_data", "content_type").Where("id = ? AND storage_key = ''", id).Scan(&legacy)
+		if result.Error != nil {
+			return fmt.Errorf("failed to read legacy CV file %d: %w", id, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil // moved by another instance in the meantime
+		}
+
+		if err := cs.blobs.Put(ctx, storageKey, bytes.NewReader(legacy.FileData), int64(len(legacy.FileData)), legacy.ContentType); err != nil {
+			return fmt.Errorf("failed to move legacy CV file %d: %w", id, err)
+		}
+		stored = true
+
+		digest := sha256.Sum256(legacy.FileData)
+		if err := tx.Unscoped().Model(&models.CVFile{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"storage_key":    storageKey,
+			"content_sha256": hex.EncodeToString(digest[:]),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update legacy CV file %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil && stored {
+		if err := cs.blobs.Delete(context.WithoutCancel(ctx), storageKey); err != nil {
+			slog.WarnContext(ctx, "failed to delete blob", "key", storageKey, "error", err)
+		}
+	}
+	return err
+}
+
 // GetStats returns statistics about CV files
-func (cs *CVStorage) GetStats() (int, int64, error) {
+func (cs *CVStorage) GetStats(ctx context.Context) (int, int64, error) {
 	type stats struct {
 		FileCount int64 `gorm:"column:file_count"`
 		TotalSize int64 `gorm:"column:total_size"`
 	}
 
 	var result stats
-	err := cs.db.Model(&models.CVFile{}).
+	err := cs.db.WithContext(ctx).Model(&models.CVFile{}).
 		Select("COUNT(*) as file_count, COALESCE(SUM(file_size), 0) as total_size").
 		Where("is_current = ?", true).
 		Scan(&result).Error
@@ -133,4 +443,4 @@ func (cs *CVStorage) GetStats() (int, int64, error) {
 	}
 
 	return int(result.FileCount), result.TotalSize, nil
-}
\ No newline at end of file
+}
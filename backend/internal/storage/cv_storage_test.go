@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"cv-backend/internal/models"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCVStorage(t *testing.T) (*CVStorage, string) {
+	t.Helper()
+	dir := t.TempDir()
+	blobs, err := NewFSBlobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCVStorage(newTestDB(t), blobs), dir
+}
+
+func upload(t *testing.T, cs *CVStorage, content, language string, scan models.ScanResult) *models.CVFile {
+	t.Helper()
+	sum := sha256.Sum256([]byte(content))
+	cvFile, err := cs.UploadCV(context.Background(), strings.NewReader(content), "cv.pdf", int64(len(content)),
+		hex.EncodeToString(sum[:]), "application/pdf", language, "", scan)
+	if err != nil {
+		t.Fatalf("UploadCV() error = %v", err)
+	}
+	return cvFile
+}
+
+// blobCount returns the number of blobs in the blob store directory
+func blobCount(t *testing.T, dir string) int {
+	t.Helper()
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			count++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+var clean = models.ScanResult{Status: models.ScanStatusClean}
+
+func TestUploadCVSharesBlobsOfEqualContent(t *testing.T) {
+	cs, dir := newTestCVStorage(t)
+
+	first := upload(t, cs, "version one", "en", clean)
+	second := upload(t, cs, "version two", "en", clean)
+	third := upload(t, cs, "version one", "en", clean)
+
+	if third.StorageKey != first.StorageKey {
+		t.Errorf("equal content got storage key %q, want %q", third.StorageKey, first.StorageKey)
+	}
+	if second.StorageKey == first.StorageKey {
+		t.Error("different content shares a storage key")
+	}
+	if got := blobCount(t, dir); got != 2 {
+		t.Errorf("blob count = %d, want 2", got)
+	}
+
+	current, err := cs.GetCurrentCV(context.Background(), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.ID != third.ID {
+		t.Errorf("current version = %d, want %d", current.ID, third.ID)
+	}
+}
+
+// countingBlobStore counts the blobs written to the wrapped store
+type countingBlobStore struct {
+	BlobStore
+	puts int
+}
+
+func (s *countingBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	s.puts++
+	return s.BlobStore.Put(ctx, key, r, size, contentType)
+}
+
+func TestUploadCVSkipsStoringDuplicates(t *testing.T) {
+	cs, _ := newTestCVStorage(t)
+	blobs := &countingBlobStore{BlobStore: cs.blobs}
+	cs.blobs = blobs
+
+	upload(t, cs, "same content", "en", clean)
+	upload(t, cs, "same content", "de", clean)
+	if blobs.puts != 1 {
+		t.Errorf("stored %d blobs for equal content, want 1", blobs.puts)
+	}
+
+	// Quarantined files are always stored on their own
+	upload(t, cs, "same content", "en", models.ScanResult{Status: models.ScanStatusQuarantined})
+	if blobs.puts != 2 {
+		t.Errorf("stored %d blobs, want 2 with the quarantined copy", blobs.puts)
+	}
+}
+
+func TestUploadCVQuarantined(t *testing.T) {
+	cs, dir := newTestCVStorage(t)
+	ctx := context.Background()
+
+	good := upload(t, cs, "same content", "en", clean)
+	flagged := upload(t, cs, "same content", "en", models.ScanResult{Status: models.ScanStatusQuarantined, Signature: "Eicar-Test-Signature"})
+
+	if flagged.IsCurrent {
+		t.Error("quarantined version is current")
+	}
+	if flagged.StorageKey == good.StorageKey {
+		t.Error("quarantined version shares the blob of a clean version")
+	}
+	if got := blobCount(t, dir); got != 2 {
+		t.Errorf("blob count = %d, want 2", got)
+	}
+
+	current, err := cs.GetCurrentCV(ctx, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.ID != good.ID {
+		t.Errorf("current version = %d, want %d", current.ID, good.ID)
+	}
+
+	if _, _, err := cs.OpenVersion(ctx, flagged.ID); !errors.Is(err, ErrCVVersionQuarantined) {
+		t.Errorf("OpenVersion() error = %v, want ErrCVVersionQuarantined", err)
+	}
+	if _, err := cs.RestoreVersion(ctx, flagged.ID); !errors.Is(err, ErrCVVersionQuarantined) {
+		t.Errorf("RestoreVersion() error = %v, want ErrCVVersionQuarantined", err)
+	}
+
+	// A later clean upload of the content does not reuse the flagged blob
+	again := upload(t, cs, "same content", "en", clean)
+	if again.StorageKey != good.StorageKey {
+		t.Errorf("clean upload got storage key %q, want %q", again.StorageKey, good.StorageKey)
+	}
+}
+
+func TestDeleteCVPermanentKeepsSharedBlobs(t *testing.T) {
+	cs, dir := newTestCVStorage(t)
+	ctx := context.Background()
+
+	upload(t, cs, "shared", "en", clean)
+	upload(t, cs, "shared", "sr", clean)
+	upload(t, cs, "english only", "en", clean)
+
+	if err := cs.DeleteCV(ctx, "en", true); err != nil {
+		t.Fatal(err)
+	}
+	// Only the content of the deleted version is gone
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("blob count after deleting a version = %d, want 1", got)
+	}
+
+	serbian, content, err := cs.OpenCurrentCV(ctx, "sr")
+	if err != nil || serbian == nil {
+		t.Fatalf("OpenCurrentCV() = %v, %v", serbian, err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if string(data) != "shared" {
+		t.Errorf("content = %q, want %q", data, "shared")
+	}
+
+	if err := cs.DeleteCV(ctx, "", true); err != nil {
+		t.Fatal(err)
+	}
+	// The old English version still references "shared"
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("blob count after deleting all current versions = %d, want 1", got)
+	}
+}
+
+func TestDeleteCVSoftDeleteKeepsBlobs(t *testing.T) {
+	cs, dir := newTestCVStorage(t)
+	ctx := context.Background()
+
+	upload(t, cs, "content", "en", clean)
+	if err := cs.DeleteCV(ctx, "en", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if current, err := cs.GetCurrentCV(ctx, "en"); err != nil || current != nil {
+		t.Errorf("GetCurrentCV() = %v, %v, want no CV", current, err)
+	}
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("blob count = %d, want 1", got)
+	}
+	if err := cs.DeleteCV(ctx, "en", false); err == nil {
+		t.Error("deleting without a current CV succeeded")
+	}
+}
+
+func TestRollbackCV(t *testing.T) {
+	cs, _ := newTestCVStorage(t)
+	ctx := context.Background()
+
+	first := upload(t, cs, "first", "en", clean)
+	upload(t, cs, "second", "en", clean)
+
+	restored, err := cs.RollbackCV(ctx, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.SHA256 != first.SHA256 {
+		t.Errorf("rolled back to %q, want content of version %d", restored.SHA256, first.ID)
+	}
+}
+
+func TestUploadCVStoresUTCTimes(t *testing.T) {
+	previous := time.Local
+	time.Local = time.FixedZone("UTC+9", 9*60*60)
+	defer func() { time.Local = previous }()
+
+	cs, _ := newTestCVStorage(t)
+	cvFile := upload(t, cs, "content", "en", clean)
+
+	var stored string
+	if err := cs.db.Raw("SELECT created_at FROM cv_files WHERE id = ?", cvFile.ID).Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stored, "+00:00") && !strings.HasSuffix(stored, "Z") {
+		t.Errorf("created_at = %q, want a UTC timestamp", stored)
+	}
+}
+
+// addLegacyFile adds the old file_data column with a row that still keeps
+// its content there
+func addLegacyFile(t *testing.T, cs *CVStorage, content string) uint {
+	t.Helper()
+	if !cs.db.Migrator().HasColumn(&models.CVFile{}, "file_data") {
+		if err := cs.db.Exec("ALTER TABLE cv_files ADD COLUMN file_data BLOB").Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	cvFile := models.CVFile{FileName: "current_cv.pdf", OriginalName: "cv.pdf", ContentType: "application/pdf", FileSize: int64(len(content)), Language: "en", IsCurrent: true}
+	if err := cs.db.Create(&cvFile).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.db.Exec("UPDATE cv_files SET file_data = ? WHERE id = ?", []byte(content), cvFile.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return cvFile.ID
+}
+
+func TestMigrateLegacyFileData(t *testing.T) {
+	cs, dir := newTestCVStorage(t)
+	ctx := context.Background()
+	addLegacyFile(t, cs, "legacy content")
+
+	if err := cs.MigrateLegacyFileData(ctx); err != nil {
+		t.Fatalf("MigrateLegacyFileData() error = %v", err)
+	}
+	if cs.db.Migrator().HasColumn(&models.CVFile{}, "file_data") {
+		t.Error("file_data column was not dropped")
+	}
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("blob count = %d, want 1", got)
+	}
+
+	cvFile, content, err := cs.OpenCurrentCV(ctx, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	if string(data) != "legacy content" || cvFile.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("migrated content = %q with SHA-256 %q", data, cvFile.SHA256)
+	}
+}
+
+func TestMigrateLegacyFileDataRemovesBlobOnFailure(t *testing.T) {
+	cs, dir := newTestCVStorage(t)
+	id := addLegacyFile(t, cs, "legacy content")
+	if err := cs.db.Exec("CREATE TRIGGER reject_migration BEFORE UPDATE OF storage_key ON cv_files BEGIN SELECT RAISE(ABORT, 'rejected'); END").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cs.MigrateLegacyFileData(context.Background()); err == nil {
+		t.Fatal("MigrateLegacyFileData() succeeded")
+	}
+	if got := blobCount(t, dir); got != 0 {
+		t.Errorf("blob count = %d, want the blob removed again", got)
+	}
+
+	var storageKey string
+	if err := cs.db.Raw("SELECT storage_key FROM cv_files WHERE id = ?", id).Scan(&storageKey).Error; err != nil {
+		t.Fatal(err)
+	}
+	if storageKey != "" || !cs.db.Migrator().HasColumn(&models.CVFile{}, "file_data") {
+		t.Errorf("storage key = %q after a failed migration, want the row and column left as they were", storageKey)
+	}
+}
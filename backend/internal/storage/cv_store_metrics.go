@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/metrics"
+	"cv-backend/internal/models"
+	"errors"
+	"io"
+	"time"
+)
+
+// metricsCVStore records the latency of every operation of a CVStore and the
+// size of uploaded files
+type metricsCVStore struct {
+	next CVStore
+}
+
+// WithMetrics wraps a CVStore so that its operations show up in /metrics
+func WithMetrics(store CVStore) CVStore {
+	return &metricsCVStore{next: store}
+}
+
+// observe records the duration of an operation. Missing versions are an
+// expected outcome, not a storage error.
+func observe(operation string, start time.Time, err error) {
+	result := "ok"
+	if err != nil && !errors.Is(err, ErrCVVersionNotFound) && !errors.Is(err, ErrCVVersionQuarantined) {
+		result = "error"
+	}
+	metrics.StorageDuration.Observe(time.Since(start).Seconds(), operation, result)
+}
+
+func (ms *metricsCVStore) UploadCV(ctx context.Context, file io.Reader, originalName string, fileSize int64, digest, contentType, language, changeNote string, scan models.ScanResult) (*models.CVFile, error) {
+	start := time.Now()
+	cvFile, err := ms.next.UploadCV(ctx, file, originalName, fileSize, digest, contentType, language, changeNote, scan)
+	observe("upload", start, err)
+	if err == nil {
+		metrics.UploadSize.Observe(float64(fileSize))
+	}
+	return cvFile, err
+}
+
+func (ms *metricsCVStore) GetCurrentCV(ctx context.Context, language string) (*models.CVFile, error) {
+	start := time.Now()
+	cvFile, err := ms.next.GetCurrentCV(ctx, language)
+	observe("get_current", start, err)
+	return cvFile, err
+}
+
+func (ms *metricsCVStore) CurrentLanguages(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	languages, err := ms.next.CurrentLanguages(ctx)
+	observe("current_languages", start, err)
+	return languages, err
+}
+
+func (ms *metricsCVStore) OpenCurrentCV(ctx context.Context, language string) (*models.CVFile, io.ReadSeekCloser, error) {
+	start := time.Now()
+	cvFile, content, err := ms.next.OpenCurrentCV(ctx, language)
+	observe("open_current", start, err)
+	return cvFile, content, err
+}
+
+func (ms *metricsCVStore) ListVersions(ctx context.Context) ([]models.CVFile, error) {
+	start := time.Now()
+	versions, err := ms.next.ListVersions(ctx)
+	observe("list_versions", start, err)
+	return versions, err
+}
+
+func (ms *metricsCVStore) GetVersion(ctx context.Context, id uint) (*models.CVFile, error) {
+	start := time.Now()
+	cvFile, err := ms.next.GetVersion(ctx, id)
+	observe("get_version", start, err)
+	return cvFile, err
+}
+
+func (ms *metricsCVStore) OpenVersion(ctx context.Context, id uint) (*models.CVFile, io.ReadSeekCloser, error) {
+	start := time.Now()
+	cvFile, content, err := ms.next.OpenVersion(ctx, id)
+	observe("open_version", start, err)
+	return cvFile, content, err
+}
+
+func (ms *metricsCVStore) RestoreVersion(ctx context.Context, id uint) (*models.CVFile, error) {
+	start := time.Now()
+	cvFile, err := ms.next.RestoreVersion(ctx, id)
+	observe("restore_version", start, err)
+	return cvFile, err
+}
+
+func (ms *metricsCVStore) RollbackCV(ctx context.Context, language string) (*models.CVFile, error) {
+	start := time.Now()
+	cvFile, err := ms.next.RollbackCV(ctx, language)
+	observe("rollback", start, err)
+	return cvFile, err
+}
+
+func (ms *metricsCVStore) DeleteCV(ctx context.Context, language string, permanent bool) error {
+	start := time.Now()
+	err := ms.next.DeleteCV(ctx, language, permanent)
+	observe("delete", start, err)
+	return err
+}
+
+func (ms *metricsCVStore) GetStats(ctx context.Context) (int, int64, error) {
+	start := time.Now()
+	count, size, err := ms.next.GetStats(ctx)
+	observe("stats", start, err)
+	return count, size, err
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"cv-backend/internal/migrations"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens an in-memory SQLite database with all migrations applied
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:  logger.Discard,
+		NowFunc: utcNow,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	// Every connection to :memory: is a database of its own
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	return db
+}
+
+func TestMigrationsCreateModelColumns(t *testing.T) {
+	previous := DB
+	DB = newTestDB(t)
+	defer func() { DB = previous }()
+
+	if err := verifySchema(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrationsRollBack(t *testing.T) {
+	db := newTestDB(t)
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolledBack, err := migrator.Down(len(status))
+	if err != nil {
+		t.Fatalf("failed to roll back migrations: %v", err)
+	}
+	if len(rolledBack) != len(status) {
+		t.Errorf("rolled back %d migrations, want %d", len(rolledBack), len(status))
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("failed to reapply migrations: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration above which queries are logged as warnings
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger writes GORM logs through slog, so that queries run with a
+// request context carry its request ID. Queries are logged at debug level,
+// slow queries as warnings and failed queries as errors.
+type gormLogger struct {
+	level logger.LogLevel
+}
+
+func newGormLogger() *gormLogger {
+	return &gormLogger{level: logger.Info}
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &gormLogger{level: level}
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs a finished query with its duration and affected rows
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		sql, rows := fc()
+		slog.ErrorContext(ctx, "query failed", "sql", sql, "rows", rows, "duration_ms", durationMs(elapsed), "error", err)
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		slog.WarnContext(ctx, "slow query", "sql", sql, "rows", rows, "duration_ms", durationMs(elapsed))
+	case l.level >= logger.Info && slog.Default().Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		slog.DebugContext(ctx, "query", "sql", sql, "rows", rows, "duration_ms", durationMs(elapsed))
+	}
+}
+
+// ParamsFilter leaves the values out of logged SQL; they include password
+// hashes, token hashes and personal data
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+// durationMs converts d to milliseconds with microsecond precision
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"cv-backend/internal/tracing"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "tracing:span"
+
+// tracingPlugin is a GORM plugin that records a client span for every
+// operation, as a child of the span in the statement's context. Queries run
+// with a request context therefore show up under its HTTP span.
+type tracingPlugin struct {
+	system attribute.KeyValue // db.system, e.g. postgresql or sqlite
+}
+
+func newTracingPlugin(dialector string) *tracingPlugin {
+	system := semconv.DBSystemKey.String(dialector)
+	switch dialector {
+	case "postgres":
+		system = semconv.DBSystemPostgreSQL
+	case "sqlite":
+		system = semconv.DBSystemSqlite
+	}
+	return &tracingPlugin{system: system}
+}
+
+func (p *tracingPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize registers the span callbacks around every GORM operation
+func (p *tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	)
+}
+
+// before starts the span of an operation if the statement belongs to a
+// sampled trace, such as a traced request
+func (p *tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if !trace.SpanFromContext(ctx).IsRecording() {
+			return
+		}
+
+		_, span := tracing.Tracer().Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(p.system),
+		)
+		tx.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func (p *tracingPlugin) after(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	// Parameters are left out: they include password and token hashes
+	span.SetAttributes(
+		semconv.DBStatement(tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if table := tx.Statement.Table; table != "" {
+		span.SetAttributes(semconv.DBSQLTable(table))
+	}
+	if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockoutPolicy describes how failed logins slow down further attempts.
+// The first freeAttempts failures cost nothing; after that every failure
+// doubles the wait, starting at one second, until lockoutAfter failures
+// lock out further attempts for lockoutDuration.
+type lockoutPolicy struct {
+	freeAttempts    int
+	lockoutAfter    int
+	lockoutDuration time.Duration
+}
+
+var (
+	// Per account, against guessing the password of a known username
+	accountPolicy = lockoutPolicy{freeAttempts: 3, lockoutAfter: 10, lockoutDuration: 15 * time.Minute}
+	// Per client IP, against trying many usernames from one address
+	ipPolicy = lockoutPolicy{freeAttempts: 5, lockoutAfter: 20, lockoutDuration: time.Hour}
+	// Per share link, against guessing the password of a leaked link
+	shareLinkPolicy = lockoutPolicy{freeAttempts: 3, lockoutAfter: 10, lockoutDuration: 15 * time.Minute}
+)
+
+// failureWindow is how long failed attempts are remembered; a failure after
+// a longer quiet period starts counting from one again
+const failureWindow = 24 * time.Hour
+
+// delay returns how long to refuse logins after the given number of failures
+func (p lockoutPolicy) delay(failures int) time.Duration {
+	if failures >= p.lockoutAfter {
+		return p.lockoutDuration
+	}
+	if failures <= p.freeAttempts {
+		return 0
+	}
+	d := time.Second << uint(failures-p.freeAttempts-1)
+	if d > p.lockoutDuration {
+		return p.lockoutDuration
+	}
+	return d
+}
+
+// LoginThrottleStorage tracks failed logins per account and per client IP
+type LoginThrottleStorage struct {
+	db    *gorm.DB
+	audit *AuditStorage
+}
+
+// NewLoginThrottleStorage creates a new LoginThrottleStorage instance that
+// records lockouts in audit
+func NewLoginThrottleStorage(db *gorm.DB, audit *AuditStorage) *LoginThrottleStorage {
+	return &LoginThrottleStorage{
+		db:    db,
+		audit: audit,
+	}
+}
+
+// RetryAfter returns how long logins for the username from the IP are
+// refused, or zero if an attempt is allowed now
+func (ls *LoginThrottleStorage) RetryAfter(ctx context.Context, username, ip string) (time.Duration, error) {
+	now := utcNow()
+	wait := time.Duration(0)
+
+	var user models.User
+	err := ls.db.WithContext(ctx).Select("locked_until").Where("username = ?", username).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return 0, fmt.Errorf("failed to check account lockout: %w", err)
+	}
+	if err == nil && user.LockedUntil != nil && user.LockedUntil.After(now) {
+		wait = user.LockedUntil.Sub(now)
+	}
+
+	ipWait, err := ls.IPRetryAfter(ctx, ip)
+	if err != nil {
+		return 0, err
+	}
+	return max(wait, ipWait), nil
+}
+
+// IPRetryAfter returns how long attempts from the IP are refused, or zero if
+// an attempt is allowed now
+func (ls *LoginThrottleStorage) IPRetryAfter(ctx context.Context, ip string) (time.Duration, error) {
+	var throttle models.LoginThrottle
+	err := ls.db.WithContext(ctx).Where("ip = ?", ip).First(&throttle).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return 0, fmt.Errorf("failed to check IP lockout: %w", err)
+	}
+	if err == nil && throttle.BlockedUntil != nil {
+		if wait := time.Until(*throttle.BlockedUntil); wait > 0 {
+			return wait, nil
+		}
+	}
+	return 0, nil
+}
+
+// RecordFailure counts a failed login for the username and the IP. Unknown
+// usernames are only counted against the IP.
+func (ls *LoginThrottleStorage) RecordFailure(ctx context.Context, username, ip string) error {
+	if err := ls.recordAccountFailure(ctx, username, ip); err != nil {
+		return err
+	}
+	return ls.RecordIPFailure(ctx, ip)
+}
+
+func (ls *LoginThrottleStorage) recordAccountFailure(ctx context.Context, username, ip string) error {
+	now := utcNow()
+
+	// Count in a single UPDATE, so concurrent failures cannot overwrite each
+	// other's increments, and compute the lockout from the returned count
+	var user models.User
+	result := ls.db.WithContext(ctx).Model(&user).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "failed_login_count"}}}).
+		Where("username = ?", username).
+		Updates(map[string]interface{}{
+			"failed_login_count":   failureCountExpr("failed_login_count", "last_failed_login_at", now),
+			"last_failed_login_at": now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to record failed login: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	failures := user.FailedLoginCount
+	delay := accountPolicy.delay(failures)
+	if delay > 0 {
+		// Never shorten a longer lockout set by a concurrent failure
+		lockedUntil := now.Add(delay)
+		if err := ls.db.WithContext(ctx).Model(&models.User{}).
+			Where("id = ? AND (locked_until IS NULL OR locked_until < ?)", user.ID, lockedUntil).
+			Update("locked_until", lockedUntil).Error; err != nil {
+			return fmt.Errorf("failed to record failed login: %w", err)
+		}
+	}
+
+	if failures == accountPolicy.lockoutAfter {
+		ls.recordAudit(ctx, &models.AuditEvent{
+			Action:   AuditAccountLocked,
+			Username: username,
+			IP:       ip,
+			Detail:   fmt.Sprintf("%d failed logins, locked for %s", failures, delay),
+		})
+	}
+	return nil
+}
+
+// RecordIPFailure counts a failed attempt from the IP, such as a wrong share
+// link password, without a username
+func (ls *LoginThrottleStorage) RecordIPFailure(ctx context.Context, ip string) error {
+	now := utcNow()
+
+	// Insert the first failure or increment the count in one statement
+	throttle := models.LoginThrottle{IP: ip, FailureCount: 1, LastFailureAt: now}
+	if err := ls.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "ip"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failure_count":   failureCountExpr("login_throttles.failure_count", "login_throttles.last_failure_at", now),
+				"last_failure_at": now,
+			}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "failure_count"}}},
+	).Create(&throttle).Error; err != nil {
+		return fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	failures := throttle.FailureCount
+	delay := ipPolicy.delay(failures)
+	if delay > 0 {
+		blockedUntil := now.Add(delay)
+		if err := ls.db.WithContext(ctx).Model(&models.LoginThrottle{}).
+			Where("ip = ? AND (blocked_until IS NULL OR blocked_until < ?)", ip, blockedUntil).
+			Update("blocked_until", blockedUntil).Error; err != nil {
+			return fmt.Errorf("failed to record failed login: %w", err)
+		}
+	}
+
+	if failures == ipPolicy.lockoutAfter {
+		ls.recordAudit(ctx, &models.AuditEvent{
+			Action: AuditIPBlocked,
+			IP:     ip,
+			Detail: fmt.Sprintf("%d failed logins, blocked for %s", failures, delay),
+		})
+	}
+	return nil
+}
+
+// failureCountExpr increments a failure count, or restarts it at one if the
+// last failure is older than failureWindow
+func failureCountExpr(countColumn, lastFailureColumn string, now time.Time) clause.Expr {
+	return gorm.Expr(
+		fmt.Sprintf("CASE WHEN %s IS NULL OR %s >= ? THEN %s + 1 ELSE 1 END", lastFailureColumn, lastFailureColumn, countColumn),
+		now.Add(-failureWindow),
+	)
+}
+
+// RecordSuccess resets the failure count of the username. The count of the
+// IP is kept, so that logging in to one account between guesses does not
+// lift the backoff on guessing others; it expires after failureWindow.
+func (ls *LoginThrottleStorage) RecordSuccess(ctx context.Context, username string) error {
+	if err := ls.db.WithContext(ctx).Model(&models.User{}).Where("username = ?", username).Updates(map[string]interface{}{
+		"failed_login_count": 0,
+		"locked_until":       nil,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+	return nil
+}
+
+// LockedUsers returns the accounts that are currently locked out
+func (ls *LoginThrottleStorage) LockedUsers(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	if err := ls.db.WithContext(ctx).Where("locked_until > ?", utcNow()).Order("locked_until DESC").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list locked accounts: %w", err)
+	}
+	return users, nil
+}
+
+// BlockedIPs returns the client IPs that are currently blocked
+func (ls *LoginThrottleStorage) BlockedIPs(ctx context.Context) ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	if err := ls.db.WithContext(ctx).Where("blocked_until > ?", utcNow()).Order("blocked_until DESC").Find(&throttles).Error; err != nil {
+		return nil, fmt.Errorf("failed to list blocked IPs: %w", err)
+	}
+	return throttles, nil
+}
+
+// UnlockUser clears the lockout and failure count of an account
+func (ls *LoginThrottleStorage) UnlockUser(ctx context.Context, username, actor string) error {
+	result := ls.db.WithContext(ctx).Model(&models.User{}).Where("username = ?", username).Updates(map[string]interface{}{
+		"failed_login_count": 0,
+		"locked_until":       nil,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unlock account: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	ls.recordAudit(ctx, &models.AuditEvent{Action: AuditAccountUnlocked, Username: username, Actor: actor})
+	return nil
+}
+
+// UnblockIP clears the block and failure count of a client IP
+func (ls *LoginThrottleStorage) UnblockIP(ctx context.Context, ip, actor string) error {
+	if err := ls.db.WithContext(ctx).Where("ip = ?", ip).Delete(&models.LoginThrottle{}).Error; err != nil {
+		return fmt.Errorf("failed to unblock IP: %w", err)
+	}
+
+	ls.recordAudit(ctx, &models.AuditEvent{Action: AuditIPUnblocked, IP: ip, Actor: actor})
+	return nil
+}
+
+// recordAudit writes an audit event; failures are logged but do not fail the request
+func (ls *LoginThrottleStorage) recordAudit(ctx context.Context, event *models.AuditEvent) {
+	if err := ls.audit.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to record audit event", "action", event.Action, "error", err)
+	}
+}
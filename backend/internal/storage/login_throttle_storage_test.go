@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestThrottle(t *testing.T) (*LoginThrottleStorage, *UserStorage) {
+	t.Helper()
+	db := newTestDB(t)
+	users := NewUserStorage(db)
+	if _, err := users.CreateUser(context.Background(), "admin", "correct horse battery", models.RoleOwner); err != nil {
+		t.Fatal(err)
+	}
+	return NewLoginThrottleStorage(db, NewAuditStorage(db)), users
+}
+
+func TestRecordFailureCountsConcurrentFailures(t *testing.T) {
+	throttle, users := newTestThrottle(t)
+	ctx := context.Background()
+
+	const attempts = 30
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := throttle.RecordFailure(ctx, "admin", "192.0.2.1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	user, err := users.GetUser(ctx, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.FailedLoginCount != attempts {
+		t.Errorf("failed login count = %d, want %d", user.FailedLoginCount, attempts)
+	}
+
+	var ip models.LoginThrottle
+	if err := throttle.db.Where("ip = ?", "192.0.2.1").First(&ip).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ip.FailureCount != attempts {
+		t.Errorf("IP failure count = %d, want %d", ip.FailureCount, attempts)
+	}
+}
+
+func TestRecordFailureLocksAccount(t *testing.T) {
+	throttle, _ := newTestThrottle(t)
+	ctx := context.Background()
+
+	for i := 0; i < accountPolicy.freeAttempts; i++ {
+		if err := throttle.RecordFailure(ctx, "admin", "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wait, err := throttle.RetryAfter(ctx, "admin", "198.51.100.1"); err != nil || wait != 0 {
+		t.Fatalf("RetryAfter() after free attempts = %v, %v, want 0", wait, err)
+	}
+
+	for i := accountPolicy.freeAttempts; i < accountPolicy.lockoutAfter; i++ {
+		if err := throttle.RecordFailure(ctx, "admin", "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The account is locked from any IP
+	wait, err := throttle.RetryAfter(ctx, "admin", "198.51.100.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= accountPolicy.lockoutDuration-time.Minute || wait > accountPolicy.lockoutDuration {
+		t.Errorf("RetryAfter() = %v, want about %v", wait, accountPolicy.lockoutDuration)
+	}
+
+	locked, err := throttle.LockedUsers(ctx)
+	if err != nil || len(locked) != 1 {
+		t.Errorf("LockedUsers() = %v, %v, want admin", locked, err)
+	}
+
+	var events []models.AuditEvent
+	if err := throttle.db.Where("action = ?", AuditAccountLocked).Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Errorf("recorded %d lockout events, want 1", len(events))
+	}
+}
+
+func TestRecordSuccessKeepsIPCount(t *testing.T) {
+	throttle, users := newTestThrottle(t)
+	ctx := context.Background()
+
+	for i := 0; i < ipPolicy.lockoutAfter; i++ {
+		if err := throttle.RecordFailure(ctx, "nobody", "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := throttle.RecordSuccess(ctx, "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	wait, err := throttle.IPRetryAfter(ctx, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 {
+		t.Error("a successful login lifted the IP block")
+	}
+
+	user, err := users.GetUser(ctx, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.FailedLoginCount != 0 {
+		t.Errorf("unknown username was counted against admin: %d failures", user.FailedLoginCount)
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	throttle, _ := newTestThrottle(t)
+	ctx := context.Background()
+
+	for i := 0; i < accountPolicy.lockoutAfter; i++ {
+		if err := throttle.RecordFailure(ctx, "admin", "192.0.2.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := throttle.UnlockUser(ctx, "admin", "owner"); err != nil {
+		t.Fatal(err)
+	}
+	if locked, err := throttle.LockedUsers(ctx); err != nil || len(locked) != 0 {
+		t.Errorf("LockedUsers() = %v, %v, want none", locked, err)
+	}
+
+	if err := throttle.UnlockUser(ctx, "nobody", "owner"); err != ErrUserNotFound {
+		t.Errorf("UnlockUser() of unknown user = %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,509 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"cv-backend/internal/models"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MemoryCVStore is a CVStore that keeps versions and their content in
+// memory. It is meant for tests and loses everything on restart.
+type MemoryCVStore struct {
+	mu       sync.Mutex
+	nextID   uint
+	files    map[uint]models.CVFile
+	contents map[uint][]byte
+}
+
+// NewMemoryCVStore creates an empty MemoryCVStore
+func NewMemoryCVStore() *MemoryCVStore {
+	return &MemoryCVStore{
+		files:    make(map[uint]models.CVFile),
+		contents: make(map[uint][]byte),
+	}
+}
+
+// nopSeekCloser adds a no-op Close to an in-memory reader
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// UploadCV records the file as the current CV for its language, unless it is
+// quarantined. Versions with the same content share a storage key.
+func (ms *MemoryCVStore) UploadCV(ctx context.Context, file io.Reader, originalName string, fileSize int64, digest, contentType, language, changeNote string, scan models.ScanResult) (*models.CVFile, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store file data: %w", err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	quarantined := scan.Status == models.ScanStatusQuarantined
+	if !quarantined {
+		ms.unsetCurrent(language, 0, now)
+	}
+
+	ms.nextID++
+	storageKey := fmt.Sprintf("memory/%d", ms.nextID)
+	for _, existing := range ms.files {
+		if !quarantined && existing.SHA256 == digest && !existing.Quarantined() {
+			storageKey = existing.StorageKey
+			break
+		}
+	}
+
+	cvFile := models.CVFile{
+		ID:            ms.nextID,
+		FileName:      "current_cv.pdf",
+		OriginalName:  originalName,
+		ContentType:   contentType,
+		FileSize:      fileSize,
+		Language:      language,
+		StorageKey:    storageKey,
+		SHA256:        digest,
+		ChangeNote:    changeNote,
+		ScanStatus:    scan.Status,
+		ScanSignature: scan.Signature,
+		IsCurrent:     !quarantined,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	ms.files[cvFile.ID] = cvFile
+	ms.contents[cvFile.ID] = content
+
+	return &cvFile, nil
+}
+
+// unsetCurrent marks the current file of a language as not current, except
+// the one with the given ID. The caller must hold the lock.
+func (ms *MemoryCVStore) unsetCurrent(language string, except uint, now time.Time) {
+	for id, cvFile := range ms.files {
+		if cvFile.IsCurrent && cvFile.Language == language && id != except {
+			cvFile.IsCurrent = false
+			cvFile.UpdatedAt = now
+			ms.files[id] = cvFile
+		}
+	}
+}
+
+// sorted returns the files matching keep, newest first. The caller must hold the lock.
+func (ms *MemoryCVStore) sorted(keep func(models.CVFile) bool) []models.CVFile {
+	result := []models.CVFile{}
+	for _, cvFile := range ms.files {
+		if keep(cvFile) {
+			result = append(result, cvFile)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+	return result
+}
+
+// GetCurrentCV returns the current CV file metadata for a language
+func (ms *MemoryCVStore) GetCurrentCV(ctx context.Context, language string) (*models.CVFile, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	current := ms.sorted(func(cvFile models.CVFile) bool {
+		return cvFile.IsCurrent && cvFile.Language == language
+	})
+	if len(current) == 0 {
+		return nil, nil
+	}
+	return &current[0], nil
+}
+
+// CurrentLanguages returns the languages that have a current CV
+func (ms *MemoryCVStore) CurrentLanguages(ctx context.Context) ([]string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	seen := make(map[string]bool)
+	languages := []string{}
+	for _, cvFile := range ms.files {
+		if cvFile.IsCurrent && !seen[cvFile.Language] {
+			seen[cvFile.Language] = true
+			languages = append(languages, cvFile.Language)
+		}
+	}
+	sort.Strings(languages)
+	return languages, nil
+}
+
+// OpenCurrentCV returns the current CV metadata for a language and a reader for its content
+func (ms *MemoryCVStore) OpenCurrentCV(ctx context.Context, language string) (*models.CVFile, io.ReadSeekCloser, error) {
+	cvFile, err := ms.GetCurrentCV(ctx, language)
+	if err != nil || cvFile == nil {
+		return nil, nil, err
+	}
+	return ms.OpenVersion(ctx, cvFile.ID)
+}
+
+// ListVersions returns all CV versions, newest first, including soft-deleted ones
+func (ms *MemoryCVStore) ListVersions(ctx context.Context) ([]models.CVFile, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return ms.sorted(func(models.CVFile) bool { return true }), nil
+}
+
+// GetVersion returns the metadata of a single CV version, including soft-deleted ones
+func (ms *MemoryCVStore) GetVersion(ctx context.Context, id uint) (*models.CVFile, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	cvFile, ok := ms.files[id]
+	if !ok {
+		return nil, ErrCVVersionNotFound
+	}
+	return &cvFile, nil
+}
+
+// OpenVersion returns a CV version and a reader for its content, unless it is quarantined
+func (ms *MemoryCVStore) OpenVersion(ctx context.Context, id uint) (*models.CVFile, io.ReadSeekCloser, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	cvFile, ok := ms.files[id]
+	if !ok {
+		return nil, nil, ErrCVVersionNotFound
+	}
+	if cvFile.Quarantined() {
+		return nil, nil, ErrCVVersionQuarantined
+	}
+	return &cvFile, nopSeekCloser{bytes.NewReader(ms.contents[id])}, nil
+}
+
+// RestoreVersion makes the given version the current CV for its language again,
+// undeleting it if necessary. Quarantined versions cannot be restored.
+func (ms *MemoryCVStore) RestoreVersion(ctx context.Context, id uint) (*models.CVFile, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	cvFile, ok := ms.files[id]
+	if !ok {
+		return nil, ErrCVVersionNotFound
+	}
+	if cvFile.Quarantined() {
+		return nil, ErrCVVersionQuarantined
+	}
+
+	now := time.Now()
+	ms.unsetCurrent(cvFile.Language, id, now)
+	cvFile.IsCurrent = true
+	cvFile.DeletedAt = gorm.DeletedAt{}
+	cvFile.UpdatedAt = now
+	ms.files[id] = cvFile
+
+	return &cvFile, nil
+}
+
+// RollbackCV restores the version of a language that was uploaded before the current one
+func (ms *MemoryCVStore) RollbackCV(ctx context.Context, language string) (*models.CVFile, error) {
+	current, err := ms.GetCurrentCV(ctx, language)
+	if err != nil {
+		return nil, err
+	}
+
+	ms.mu.Lock()
+	previous := ms.sorted(func(cvFile models.CVFile) bool {
+		return !cvFile.IsCurrent && !cvFile.DeletedAt.Valid && !cvFile.Quarantined() && cvFile.Language == language &&
+			(current == nil || cvFile.ID < current.ID)
+	})
+	ms.mu.Unlock()
+
+	if len(previous) == 0 {
+		return nil, ErrCVVersionNotFound
+	}
+	return ms.RestoreVersion(ctx, previous[0].ID)
+}
+
+// DeleteCV deletes the current CV of a language, or of all languages if
+// language is empty, either softly or permanently
+func (ms *MemoryCVStore) DeleteCV(ctx context.Context, language string, permanent bool) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	current := ms.sorted(func(cvFile models.CVFile) bool {
+		return cvFile.IsCurrent && (language == "" || cvFile.Language == language)
+	})
+	if len(current) == 0 {
+		return fmt.Errorf("no CV found to delete")
+	}
+
+	now := time.Now()
+	for _, cvFile := range current {
+		if permanent {
+			delete(ms.files, cvFile.ID)
+			delete(ms.contents, cvFile.ID)
+			continue
+		}
+		cvFile.IsCurrent = false
+		cvFile.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		cvFile.UpdatedAt = now
+		ms.files[cvFile.ID] = cvFile
+	}
+
+	return nil
+}
+
+// GetStats returns the number and total size of the current CV files
+func (ms *MemoryCVStore) GetStats(ctx context.Context) (int, int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	count, size := 0, int64(0)
+	for _, cvFile := range ms.files {
+		if cvFile.IsCurrent {
+			count++
+			size += cvFile.FileSize
+		}
+	}
+	return count, size, nil
+}
+
+// MemoryUserStore is a UserStore that keeps accounts in memory. It is meant
+// for tests; features that read the users table directly, such as two-factor
+// authentication, login lockouts and API keys, still need the database.
+type MemoryUserStore struct {
+	mu     sync.Mutex
+	nextID uint
+	users  map[uint]models.User
+}
+
+// NewMemoryUserStore creates an empty MemoryUserStore
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{
+		users: make(map[uint]models.User),
+	}
+}
+
+// byUsername returns the ID of a user. The caller must hold the lock.
+func (ms *MemoryUserStore) byUsername(username string) (uint, bool) {
+	for id, user := range ms.users {
+		if user.Username == username {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// owners counts the users with the owner role. The caller must hold the lock.
+func (ms *MemoryUserStore) owners() int {
+	count := 0
+	for _, user := range ms.users {
+		if user.Role == models.RoleOwner {
+			count++
+		}
+	}
+	return count
+}
+
+// InitializeDefaultUser creates the default admin user as owner when there are no users yet
+func (ms *MemoryUserStore) InitializeDefaultUser(ctx context.Context, username, password string) error {
+	ms.mu.Lock()
+	empty := len(ms.users) == 0
+	ms.mu.Unlock()
+
+	if !empty {
+		return nil
+	}
+	if password == "" {
+		return errors.New("ADMIN_PASSWORD is required to create the first user")
+	}
+	_, err := ms.CreateUser(ctx, username, password, models.RoleOwner)
+	return err
+}
+
+// CreateUser adds a user who has to change the password on first login
+func (ms *MemoryUserStore) CreateUser(ctx context.Context, username, password, role string) (*models.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.byUsername(username); ok {
+		return nil, ErrUsernameTaken
+	}
+
+	now := time.Now()
+	ms.nextID++
+	user := models.User{
+		ID:                 ms.nextID,
+		Username:           username,
+		PasswordHash:       string(hashedPassword),
+		Role:               role,
+		FirstLogin:         true,
+		LastPasswordChange: now,
+		LastLoginAt:        now,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	ms.users[user.ID] = user
+
+	return &user, nil
+}
+
+// ListUsers returns all users ordered by username
+func (ms *MemoryUserStore) ListUsers(ctx context.Context) ([]models.User, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	users := make([]models.User, 0, len(ms.users))
+	for _, user := range ms.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+// GetUser retrieves a user by username
+func (ms *MemoryUserStore) GetUser(ctx context.Context, username string) (*models.User, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	id, ok := ms.byUsername(username)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user := ms.users[id]
+	return &user, nil
+}
+
+// GetUserByID retrieves a user by ID
+func (ms *MemoryUserStore) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	user, ok := ms.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// ValidatePassword validates a user's password and updates login stats
+func (ms *MemoryUserStore) ValidatePassword(ctx context.Context, username, password string) (*models.User, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	id, ok := ms.byUsername(username)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user := ms.users[id]
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, fmt.Errorf("invalid password")
+	}
+
+	user.LoginCount++
+	user.LastLoginAt = time.Now()
+	user.UpdatedAt = user.LastLoginAt
+	ms.users[id] = user
+	return &user, nil
+}
+
+// setPassword replaces a user's password and invalidates their access tokens
+func (ms *MemoryUserStore) setPassword(id uint, password string, firstLogin bool) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	user, ok := ms.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	user.PasswordHash = string(hashedPassword)
+	user.FirstLogin = firstLogin
+	user.LastPasswordChange = time.Now()
+	user.UpdatedAt = user.LastPasswordChange
+	user.TokenVersion++
+	ms.users[id] = user
+	return nil
+}
+
+// ChangePassword changes a user's password
+func (ms *MemoryUserStore) ChangePassword(ctx context.Context, username, newPassword string) error {
+	user, err := ms.GetUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	return ms.setPassword(user.ID, newPassword, false)
+}
+
+// ResetPassword sets a new password chosen by an admin, to be changed on the next login
+func (ms *MemoryUserStore) ResetPassword(ctx context.Context, id uint, password string) error {
+	return ms.setPassword(id, password, true)
+}
+
+// SetRole changes the role of a user. The last owner cannot be demoted.
+func (ms *MemoryUserStore) SetRole(ctx context.Context, id uint, role string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	user, ok := ms.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if user.Role == models.RoleOwner && role != models.RoleOwner && ms.owners() <= 1 {
+		return ErrLastOwner
+	}
+	user.Role = role
+	ms.users[id] = user
+	return nil
+}
+
+// DeleteUser removes a user. The last owner cannot be deleted.
+func (ms *MemoryUserStore) DeleteUser(ctx context.Context, id uint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	user, ok := ms.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if user.Role == models.RoleOwner && ms.owners() <= 1 {
+		return ErrLastOwner
+	}
+	delete(ms.users, id)
+	return nil
+}
+
+// IncrementTokenVersion invalidates all access tokens issued to a user so far
+func (ms *MemoryUserStore) IncrementTokenVersion(ctx context.Context, username string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	id, ok := ms.byUsername(username)
+	if !ok {
+		return ErrUserNotFound
+	}
+	user := ms.users[id]
+	user.TokenVersion++
+	ms.users[id] = user
+	return nil
+}
+
+var (
+	_ CVStore   = (*MemoryCVStore)(nil)
+	_ UserStore = (*MemoryUserStore)(nil)
+)
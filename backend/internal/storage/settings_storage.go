@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SettingPublicAccess controls whether the CV can be fetched without a share link
+const SettingPublicAccess = "public_access"
+
+// SettingsStorage handles runtime settings changed by admins
+type SettingsStorage struct {
+	db *gorm.DB
+}
+
+// NewSettingsStorage creates a new SettingsStorage instance
+func NewSettingsStorage(db *gorm.DB) *SettingsStorage {
+	return &SettingsStorage{
+		db: db,
+	}
+}
+
+// GetBool returns a boolean setting, or fallback if it was never set
+func (ss *SettingsStorage) GetBool(ctx context.Context, key string, fallback bool) (bool, error) {
+	var setting models.Setting
+	if err := ss.db.WithContext(ctx).Where("key = ?", key).First(&setting).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fallback, nil
+		}
+		return fallback, fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+
+	value, err := strconv.ParseBool(setting.Value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid value for setting %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetBool stores a boolean setting
+func (ss *SettingsStorage) SetBool(ctx context.Context, key string, value bool) error {
+	setting := models.Setting{Key: key, Value: strconv.FormatBool(value)}
+	if err := ss.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error; err != nil {
+		return fmt.Errorf("failed to save setting %s: %w", key, err)
+	}
+	return nil
+}
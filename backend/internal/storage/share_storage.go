@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"cv-backend/internal/models"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrShareLinkNotFound is returned when no share link matches a token or ID
+	ErrShareLinkNotFound = errors.New("share link not found")
+	// ErrShareLinkExhausted is returned when a share link reached its download cap
+	ErrShareLinkExhausted = errors.New("share link download limit reached")
+)
+
+// ShareLinkStorage handles private share links and their access log
+type ShareLinkStorage struct {
+	db *gorm.DB
+}
+
+// NewShareLinkStorage creates a new ShareLinkStorage instance
+func NewShareLinkStorage(db *gorm.DB) *ShareLinkStorage {
+	return &ShareLinkStorage{
+		db: db,
+	}
+}
+
+// newShareToken generates the unguessable part of a share link URL
+func newShareToken() (string, error) {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// CreateShareLink stores a new share link with a fresh token. An empty
+// password creates a link that does not ask for one.
+func (ss *ShareLinkStorage) CreateShareLink(ctx context.Context, link *models.ShareLink, password string) error {
+	token, err := newShareToken()
+	if err != nil {
+		return err
+	}
+	link.Token = token
+	link.ExpiresAt = utcTime(link.ExpiresAt)
+
+	if password != "" {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		link.PasswordHash = string(hashedPassword)
+	}
+
+	if err := ss.db.WithContext(ctx).Create(link).Error; err != nil {
+		return fmt.Errorf("failed to create share link: %w", err)
+	}
+	return nil
+}
+
+// ListShareLinks returns all share links, newest first
+func (ss *ShareLinkStorage) ListShareLinks(ctx context.Context) ([]models.ShareLink, error) {
+	var links []models.ShareLink
+	if err := ss.db.WithContext(ctx).Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+	return links, nil
+}
+
+// GetShareLink returns a share link by ID
+func (ss *ShareLinkStorage) GetShareLink(ctx context.Context, id uint) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := ss.db.WithContext(ctx).First(&link, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	return &link, nil
+}
+
+// GetShareLinkByToken returns the share link a URL token belongs to
+func (ss *ShareLinkStorage) GetShareLinkByToken(ctx context.Context, token string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := ss.db.WithContext(ctx).Where("token = ?", token).First(&link).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	return &link, nil
+}
+
+// CheckPassword reports whether password unlocks the link
+func (ss *ShareLinkStorage) CheckPassword(link *models.ShareLink, password string) bool {
+	if link.PasswordHash == "" {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) == nil
+}
+
+// RecordUnlockFailure counts a wrong password for the link and refuses
+// further attempts for a while once it has seen too many
+func (ss *ShareLinkStorage) RecordUnlockFailure(ctx context.Context, link *models.ShareLink) error {
+	now := utcNow()
+
+	var updated models.ShareLink
+	result := ss.db.WithContext(ctx).Model(&updated).Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_unlock_count"}}}).
+		Where("id = ?", link.ID).
+		Updates(map[string]interface{}{
+			"failed_unlock_count":   failureCountExpr("failed_unlock_count", "last_failed_unlock_at", now),
+			"last_failed_unlock_at": now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to record share link password failure: %w", result.Error)
+	}
+
+	if delay := shareLinkPolicy.delay(updated.FailedUnlockCount); delay > 0 {
+		blockedUntil := now.Add(delay)
+		if err := ss.db.WithContext(ctx).Model(&models.ShareLink{}).
+			Where("id = ? AND (unlock_blocked_until IS NULL OR unlock_blocked_until < ?)", link.ID, blockedUntil).
+			Update("unlock_blocked_until", blockedUntil).Error; err != nil {
+			return fmt.Errorf("failed to record share link password failure: %w", err)
+		}
+	}
+	return nil
+}
+
+// RecordUnlockSuccess resets the failed password count of the link
+func (ss *ShareLinkStorage) RecordUnlockSuccess(ctx context.Context, link *models.ShareLink) error {
+	if link.FailedUnlockCount == 0 {
+		return nil
+	}
+	if err := ss.db.WithContext(ctx).Model(&models.ShareLink{}).Where("id = ?", link.ID).Updates(map[string]interface{}{
+		"failed_unlock_count":  0,
+		"unlock_blocked_until": nil,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to reset share link password failures: %w", err)
+	}
+	link.FailedUnlockCount = 0
+	link.UnlockBlockedUntil = nil
+	return nil
+}
+
+// RevokeShareLink disables a share link for good
+func (ss *ShareLinkStorage) RevokeShareLink(ctx context.Context, id uint) (*models.ShareLink, error) {
+	result := ss.db.WithContext(ctx).Model(&models.ShareLink{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", utcNow())
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to revoke share link: %w", result.Error)
+	}
+	return ss.GetShareLink(ctx, id)
+}
+
+// ClaimDownload counts one download against the link's cap. The check and
+// the increment happen in one statement, so concurrent requests cannot
+// exceed the cap.
+func (ss *ShareLinkStorage) ClaimDownload(ctx context.Context, link *models.ShareLink) error {
+	now := utcNow()
+	result := ss.db.WithContext(ctx).Model(&models.ShareLink{}).
+		Where("id = ? AND (max_downloads = 0 OR download_count < max_downloads)", link.ID).
+		Updates(map[string]interface{}{
+			"download_count":   gorm.Expr("download_count + ?", 1),
+			"last_accessed_at": now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to count share link download: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrShareLinkExhausted
+	}
+
+	link.DownloadCount++
+	link.LastAccessedAt = &now
+	return nil
+}
+
+// ListAccesses returns the most recent access events of a share link, newest first
+func (ss *ShareLinkStorage) ListAccesses(ctx context.Context, id uint, limit int) ([]models.CVAccessEvent, error) {
+	var events []models.CVAccessEvent
+	if err := ss.db.WithContext(ctx).Where("share_link_id = ?", id).
+		Order("occurred_at DESC").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to list share link accesses: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestClaimDownloadRespectsCap(t *testing.T) {
+	ss := NewShareLinkStorage(newTestDB(t))
+	ctx := context.Background()
+
+	link := &models.ShareLink{Label: "recruiter", MaxDownloads: 3}
+	if err := ss.CreateShareLink(ctx, link, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claimed := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claim := *link
+			err := ss.ClaimDownload(ctx, &claim)
+			if err != nil && !errors.Is(err, ErrShareLinkExhausted) {
+				t.Error(err)
+			}
+			if err == nil {
+				mu.Lock()
+				claimed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if claimed != link.MaxDownloads {
+		t.Errorf("claimed %d downloads, want %d", claimed, link.MaxDownloads)
+	}
+}
+
+func TestRecordUnlockFailureBlocksLink(t *testing.T) {
+	ss := NewShareLinkStorage(newTestDB(t))
+	ctx := context.Background()
+
+	link := &models.ShareLink{Label: "recruiter"}
+	if err := ss.CreateShareLink(ctx, link, "a long password"); err != nil {
+		t.Fatal(err)
+	}
+	if ss.CheckPassword(link, "wrong") || !ss.CheckPassword(link, "a long password") {
+		t.Fatal("CheckPassword() does not match the link password")
+	}
+
+	for i := 0; i < shareLinkPolicy.lockoutAfter; i++ {
+		if err := ss.RecordUnlockFailure(ctx, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored, err := ss.GetShareLink(ctx, link.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.FailedUnlockCount != shareLinkPolicy.lockoutAfter {
+		t.Errorf("failed unlock count = %d, want %d", stored.FailedUnlockCount, shareLinkPolicy.lockoutAfter)
+	}
+	if stored.UnlockBlockedUntil == nil {
+		t.Fatal("link is not blocked")
+	}
+
+	if err := ss.RecordUnlockSuccess(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = ss.GetShareLink(ctx, link.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.FailedUnlockCount != 0 || stored.UnlockBlockedUntil != nil {
+		t.Errorf("after success: count = %d, blocked until %v", stored.FailedUnlockCount, stored.UnlockBlockedUntil)
+	}
+}
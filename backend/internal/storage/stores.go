@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// CVStore stores CV versions and their content. CVStorage keeps them in the
+// database and the blob store; MemoryCVStore keeps them in memory for tests.
+type CVStore interface {
+	UploadCV(ctx context.Context, file io.Reader, originalName string, fileSize int64, digest, contentType, language, changeNote string, scan models.ScanResult) (*models.CVFile, error)
+	GetCurrentCV(ctx context.Context, language string) (*models.CVFile, error)
+	CurrentLanguages(ctx context.Context) ([]string, error)
+	OpenCurrentCV(ctx context.Context, language string) (*models.CVFile, io.ReadSeekCloser, error)
+	ListVersions(ctx context.Context) ([]models.CVFile, error)
+	GetVersion(ctx context.Context, id uint) (*models.CVFile, error)
+	OpenVersion(ctx context.Context, id uint) (*models.CVFile, io.ReadSeekCloser, error)
+	RestoreVersion(ctx context.Context, id uint) (*models.CVFile, error)
+	RollbackCV(ctx context.Context, language string) (*models.CVFile, error)
+	DeleteCV(ctx context.Context, language string, permanent bool) error
+	GetStats(ctx context.Context) (int, int64, error)
+}
+
+// UserStore stores user accounts and their passwords. UserStorage keeps them
+// in the database; MemoryUserStore keeps them in memory for tests.
+type UserStore interface {
+	InitializeDefaultUser(ctx context.Context, username, password string) error
+	CreateUser(ctx context.Context, username, password, role string) (*models.User, error)
+	ListUsers(ctx context.Context) ([]models.User, error)
+	GetUser(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	ValidatePassword(ctx context.Context, username, password string) (*models.User, error)
+	ChangePassword(ctx context.Context, username, newPassword string) error
+	ResetPassword(ctx context.Context, id uint, password string) error
+	SetRole(ctx context.Context, id uint, role string) error
+	DeleteUser(ctx context.Context, id uint) error
+	IncrementTokenVersion(ctx context.Context, username string) error
+}
+
+var (
+	_ CVStore   = (*CVStorage)(nil)
+	_ UserStore = (*UserStorage)(nil)
+)
+
+// Stores bundles the storages the HTTP handlers depend on. Only Users and CVs
+// are interfaces; the other storages are concrete types that need the SQL
+// database, so tests run them on SQLite with the migrations applied.
+type Stores struct {
+	Users         UserStore
+	CVs           CVStore
+	Content       *ContentStorage
+	Analytics     *AnalyticsStorage
+	ShareLinks    *ShareLinkStorage
+	Settings      *SettingsStorage
+	RefreshTokens *RefreshTokenStorage
+	TwoFactor     *TwoFactorStorage
+	LoginThrottle *LoginThrottleStorage
+	Audit         *AuditStorage
+	APIKeys       *APIKeyStorage
+}
+
+// NewStores creates the database-backed storages. Users and CVs can be
+// replaced afterwards, e.g. with the in-memory stores in tests.
+func NewStores(db *gorm.DB, blobs BlobStore) *Stores {
+	audit := NewAuditStorage(db)
+	return &Stores{
+		Users:         NewUserStorage(db),
+		CVs:           WithMetrics(NewCVStorage(db, blobs)),
+		Content:       NewContentStorage(db),
+		Analytics:     NewAnalyticsStorage(db),
+		ShareLinks:    NewShareLinkStorage(db),
+		Settings:      NewSettingsStorage(db),
+		RefreshTokens: NewRefreshTokenStorage(db),
+		TwoFactor:     NewTwoFactorStorage(db),
+		LoginThrottle: NewLoginThrottleStorage(db, audit),
+		Audit:         audit,
+		APIKeys:       NewAPIKeyStorage(db),
+	}
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"cv-backend/internal/auth"
+	"cv-backend/internal/models"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// RefreshTokenStorage handles the refresh tokens of logged-in sessions
+type RefreshTokenStorage struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenStorage creates a new RefreshTokenStorage instance
+func NewRefreshTokenStorage(db *gorm.DB) *RefreshTokenStorage {
+	return &RefreshTokenStorage{
+		db: db,
+	}
+}
+
+// IssueRefreshToken starts a new session for a user and returns its refresh token
+func (rs *RefreshTokenStorage) IssueRefreshToken(ctx context.Context, userID uint, userAgent string) (string, error) {
+	family := make([]byte, 16)
+	if _, err := rand.Read(family); err != nil {
+		return "", fmt.Errorf("failed to generate token family: %w", err)
+	}
+
+	// Expired tokens are of no use any more
+	if err := rs.db.WithContext(ctx).Where("expires_at < ?", utcNow()).Delete(&models.RefreshToken{}).Error; err != nil {
+		slog.WarnContext(ctx, "failed to delete expired refresh tokens", "error", err)
+	}
+
+	return rs.createToken(rs.db.WithContext(ctx), userID, hex.EncodeToString(family), userAgent)
+}
+
+func (rs *RefreshTokenStorage) createToken(db *gorm.DB, userID uint, familyID, userAgent string) (string, error) {
+	token, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken := models.RefreshToken{
+		UserID:    userID,
+		TokenHash: auth.HashRefreshToken(token),
+		FamilyID:  familyID,
+		UserAgent: userAgent,
+		ExpiresAt: utcNow().Add(auth.RefreshTokenTTL()),
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one of the same
+// session and returns the session's user. A token can only be used once:
+// presenting an already rotated token revokes the whole session, since it
+// means the token was copied.
+func (rs *RefreshTokenStorage) RotateRefreshToken(ctx context.Context, token, userAgent string) (*models.User, string, error) {
+	var user models.User
+	var newToken string
+	reused := false
+
+	err := rs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Where("token_hash = ?", auth.HashRefreshToken(token)).First(&current).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidRefreshToken
+			}
+			return fmt.Errorf("failed to get refresh token: %w", err)
+		}
+
+		if current.RevokedAt != nil {
+			reused = true
+			return ErrInvalidRefreshToken
+		}
+		if !utcNow().Before(current.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Revoke only if nobody else rotated the token in the meantime
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", utcNow())
+		if result.Error != nil {
+			return fmt.Errorf("failed to revoke refresh token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.First(&user, current.UserID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidRefreshToken
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		var err error
+		newToken, err = rs.createToken(tx, current.UserID, current.FamilyID, userAgent)
+		return err
+	})
+
+	if reused {
+		if err := rs.revokeFamily(ctx, token); err != nil {
+			slog.WarnContext(ctx, "failed to revoke reused refresh token session", "error", err)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &user, newToken, nil
+}
+
+// revokeFamily revokes every token of the session a refresh token belongs to
+func (rs *RefreshTokenStorage) revokeFamily(ctx context.Context, token string) error {
+	var current models.RefreshToken
+	if err := rs.db.WithContext(ctx).Where("token_hash = ?", auth.HashRefreshToken(token)).First(&current).Error; err != nil {
+		return fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if err := rs.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", current.FamilyID).
+		Update("revoked_at", utcNow()).Error; err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeRefreshToken ends the session a refresh token belongs to
+func (rs *RefreshTokenStorage) RevokeRefreshToken(ctx context.Context, token string) error {
+	err := rs.revokeFamily(ctx, token)
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	return err
+}
+
+// RevokeAllForUser ends every session of a user
+func (rs *RefreshTokenStorage) RevokeAllForUser(ctx context.Context, userID uint) error {
+	if err := rs.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", utcNow()).Error; err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
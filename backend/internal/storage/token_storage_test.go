@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/models"
+	"errors"
+	"testing"
+)
+
+func newTestRefreshTokens(t *testing.T) (*RefreshTokenStorage, *models.User) {
+	t.Helper()
+	db := newTestDB(t)
+	user, err := NewUserStorage(db).CreateUser(context.Background(), "admin", "correct horse battery", models.RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRefreshTokenStorage(db), user
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	rs, user := newTestRefreshTokens(t)
+	ctx := context.Background()
+
+	token, err := rs.IssueRefreshToken(ctx, user.ID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotatedUser, rotated, err := rs.RotateRefreshToken(ctx, token, "test")
+	if err != nil {
+		t.Fatalf("RotateRefreshToken() error = %v", err)
+	}
+	if rotatedUser.ID != user.ID || rotated == "" || rotated == token {
+		t.Errorf("RotateRefreshToken() = user %d, token %q", rotatedUser.ID, rotated)
+	}
+	if _, _, err := rs.RotateRefreshToken(ctx, "unknown", "test"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("rotating an unknown token: error = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestReusedRefreshTokenRevokesFamily(t *testing.T) {
+	rs, user := newTestRefreshTokens(t)
+	ctx := context.Background()
+
+	stolen, err := rs.IssueRefreshToken(ctx, user.ID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rs.IssueRefreshToken(ctx, user.ID, "other device")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, current, err := rs.RotateRefreshToken(ctx, stolen, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, latest, err := rs.RotateRefreshToken(ctx, current, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Presenting the rotated token again ends its whole session
+	if _, _, err := rs.RotateRefreshToken(ctx, stolen, "attacker"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reusing a rotated token: error = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, _, err := rs.RotateRefreshToken(ctx, latest, "test"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("latest token of the session after reuse: error = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// Other sessions of the user are not affected
+	if _, _, err := rs.RotateRefreshToken(ctx, other, "other device"); err != nil {
+		t.Errorf("token of another session: error = %v", err)
+	}
+}
+
+func TestRevokeRefreshTokens(t *testing.T) {
+	rs, user := newTestRefreshTokens(t)
+	ctx := context.Background()
+
+	first, err := rs.IssueRefreshToken(ctx, user.ID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := rs.IssueRefreshToken(ctx, user.ID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rs.RevokeRefreshToken(ctx, first); err != nil {
+		t.Fatalf("RevokeRefreshToken() error = %v", err)
+	}
+	if _, _, err := rs.RotateRefreshToken(ctx, first, "test"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("revoked token: error = %v, want ErrInvalidRefreshToken", err)
+	}
+	if err := rs.RevokeRefreshToken(ctx, "unknown"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("revoking an unknown token: error = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	if err := rs.RevokeAllForUser(ctx, user.ID); err != nil {
+		t.Fatalf("RevokeAllForUser() error = %v", err)
+	}
+	if _, _, err := rs.RotateRefreshToken(ctx, second, "test"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("token after RevokeAllForUser(): error = %v, want ErrInvalidRefreshToken", err)
+	}
+}
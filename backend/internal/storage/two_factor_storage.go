@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"cv-backend/internal/auth"
+	"cv-backend/internal/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is the number of recovery codes issued at a time
+const recoveryCodeCount = 10
+
+var (
+	// ErrTwoFactorNotEnrolled is returned when activating 2FA before enrolling
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not enrolled")
+	// ErrInvalidTwoFactorCode is returned for wrong, reused or expired codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+// TwoFactorStorage handles TOTP secrets and recovery codes of users
+type TwoFactorStorage struct {
+	db *gorm.DB
+}
+
+// NewTwoFactorStorage creates a new TwoFactorStorage instance
+func NewTwoFactorStorage(db *gorm.DB) *TwoFactorStorage {
+	return &TwoFactorStorage{
+		db: db,
+	}
+}
+
+// Enroll stores a new, not yet active TOTP secret for a user
+func (ts *TwoFactorStorage) Enroll(ctx context.Context, user *models.User) (string, error) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	if err := ts.db.WithContext(ctx).Model(user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return "", fmt.Errorf("failed to store TOTP secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// Activate turns on 2FA after the user proved with a code that their
+// authenticator works, and returns the initial recovery codes
+func (ts *TwoFactorStorage) Activate(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, utcNow(), user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err := ts.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns off 2FA and removes the secret and recovery codes
+func (ts *TwoFactorStorage) Disable(ctx context.Context, user *models.User) error {
+	return ts.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// VerifyCode checks a TOTP code or an unused recovery code of a user with
+// 2FA enabled. Each code is accepted only once.
+func (ts *TwoFactorStorage) VerifyCode(ctx context.Context, user *models.User, code string) error {
+	if step, ok := auth.ValidateTOTP(user.TOTPSecret, code, utcNow(), user.TOTPLastStep); ok {
+		// Advance the last used step atomically so concurrent requests cannot reuse the code
+		result := ts.db.WithContext(ctx).Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record TOTP code: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	result := ts.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, auth.HashRecoveryCode(code)).
+		Update("used_at", utcNow())
+	if result.Error != nil {
+		return fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of a user with new ones
+func (ts *TwoFactorStorage) RegenerateRecoveryCodes(ctx context.Context, user *models.User) ([]string, error) {
+	var codes []string
+	err := ts.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RemainingRecoveryCodes returns the number of unused recovery codes of a user
+func (ts *TwoFactorStorage) RemainingRecoveryCodes(ctx context.Context, user *models.User) (int, error) {
+	var count int64
+	if err := ts.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return int(count), nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	rows := make([]models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: auth.HashRecoveryCode(code)})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
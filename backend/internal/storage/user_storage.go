@@ -1,71 +1,242 @@
 package storage
 
 import (
+	"context"
 	"cv-backend/internal/models"
+	"errors"
 	"fmt"
-	"time"
+	"log/slog"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrUserNotFound is returned when no user matches a username or ID
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUsernameTaken is returned when creating a user with an existing username
+	ErrUsernameTaken = errors.New("username already exists")
+	// ErrLastOwner is returned when removing the owner role from the only owner
+	ErrLastOwner = errors.New("cannot remove the last owner")
+)
+
 // UserStorage handles GORM-based user operations
 type UserStorage struct {
 	db *gorm.DB
 }
 
 // NewUserStorage creates a new UserStorage instance
-func NewUserStorage() *UserStorage {
+func NewUserStorage(db *gorm.DB) *UserStorage {
 	return &UserStorage{
-		db: GetDB(),
+		db: db,
+	}
+}
+
+// InitializeDefaultUser creates the default admin user as owner when there
+// are no users yet. Databases from before roles existed have their admin
+// promoted to owner, so there is always someone who can manage users.
+func (us *UserStorage) InitializeDefaultUser(ctx context.Context, username, password string) error {
+	var count int64
+	if err := us.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check if users exist: %w", err)
 	}
+
+	if count > 0 {
+		return us.ensureOwner(ctx, username)
+	}
+	if password == "" {
+		return errors.New("ADMIN_PASSWORD is required to create the first user")
+	}
+
+	_, err := us.CreateUser(ctx, username, password, models.RoleOwner)
+	return err
 }
 
-// InitializeDefaultUser creates the default admin user if it doesn't exist
-func (us *UserStorage) InitializeDefaultUser(username, password string) error {
+// ensureOwner promotes the given user, or the oldest user if it does not
+// exist, to owner when no owner exists
+func (us *UserStorage) ensureOwner(ctx context.Context, preferred string) error {
+	owners, err := us.countOwners(us.db.WithContext(ctx))
+	if err != nil || owners > 0 {
+		return err
+	}
+
 	var user models.User
-	
-	// Check if user already exists
-	result := us.db.Where("username = ?", username).First(&user)
-	if result.Error == nil {
-		return nil // User already exists, nothing to do
+	err = us.db.WithContext(ctx).Where("username = ?", preferred).First(&user).Error
+	if err == gorm.ErrRecordNotFound {
+		err = us.db.WithContext(ctx).Order("id").First(&user).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find user to promote: %w", err)
+	}
+
+	if err := us.db.WithContext(ctx).Model(&user).Update("role", models.RoleOwner).Error; err != nil {
+		return fmt.Errorf("failed to promote user: %w", err)
 	}
-	
-	if result.Error != gorm.ErrRecordNotFound {
-		return fmt.Errorf("failed to check if user exists: %w", result.Error)
+	slog.InfoContext(ctx, "promoted user to owner", "username", user.Username)
+	return nil
+}
+
+func (us *UserStorage) countOwners(db *gorm.DB) (int64, error) {
+	var owners int64
+	if err := db.Model(&models.User{}).Where("role = ?", models.RoleOwner).Count(&owners).Error; err != nil {
+		return 0, fmt.Errorf("failed to count owners: %w", err)
+	}
+	return owners, nil
+}
+
+// CreateUser adds a user who has to change the password on first login
+func (us *UserStorage) CreateUser(ctx context.Context, username, password, role string) (*models.User, error) {
+	var existing int64
+	if err := us.db.WithContext(ctx).Model(&models.User{}).Where("username = ?", username).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check if user exists: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrUsernameTaken
 	}
 
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
 	// Create the user
 	newUser := models.User{
 		Username:           username,
 		PasswordHash:       string(hashedPassword),
+		Role:               role,
 		FirstLogin:         true,
 		LoginCount:         0,
-		LastPasswordChange: time.Now(),
-		LastLoginAt:        time.Now(),
+		LastPasswordChange: utcNow(),
+		LastLoginAt:        utcNow(),
+	}
+
+	if err := us.db.WithContext(ctx).Create(&newUser).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return &newUser, nil
+}
+
+// ListUsers returns all users ordered by username
+func (us *UserStorage) ListUsers(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	if err := us.db.WithContext(ctx).Order("username").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// GetUserByID retrieves a user by ID
+func (us *UserStorage) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := us.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// SetRole changes the role of a user. The last owner cannot be demoted.
+func (us *UserStorage) SetRole(ctx context.Context, id uint, role string) error {
+	return us.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		if user.Role == models.RoleOwner && role != models.RoleOwner {
+			owners, err := us.countOwners(tx)
+			if err != nil {
+				return err
+			}
+			if owners <= 1 {
+				return ErrLastOwner
+			}
+		}
+
+		if err := tx.Model(&user).Update("role", role).Error; err != nil {
+			return fmt.Errorf("failed to update role: %w", err)
+		}
+		return nil
+	})
+}
+
+// ResetPassword sets a new password chosen by an admin. The user has to
+// change it on the next login, and their access tokens stop working.
+func (us *UserStorage) ResetPassword(ctx context.Context, id uint, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	if err := us.db.Create(&newUser).Error; err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+	result := us.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password_hash":        string(hashedPassword),
+		"first_login":          true,
+		"last_password_change": utcNow(),
+		"token_version":        gorm.Expr("token_version + ?", 1),
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to reset password: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
+// DeleteUser removes a user with their sessions, API keys and recovery codes.
+// The last owner cannot be deleted.
+func (us *UserStorage) DeleteUser(ctx context.Context, id uint) error {
+	return us.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		if user.Role == models.RoleOwner {
+			owners, err := us.countOwners(tx)
+			if err != nil {
+				return err
+			}
+			if owners <= 1 {
+				return ErrLastOwner
+			}
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.RefreshToken{}).Error; err != nil {
+			return fmt.Errorf("failed to delete sessions: %w", err)
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.APIKey{}).Error; err != nil {
+			return fmt.Errorf("failed to delete API keys: %w", err)
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+}
+
 // ValidatePassword validates a user's password and updates login stats
-func (us *UserStorage) ValidatePassword(username, password string) (*models.User, error) {
+func (us *UserStorage) ValidatePassword(ctx context.Context, username, password string) (*models.User, error) {
 	var user models.User
-	
+
 	// Find user by username
-	if err := us.db.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := us.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -78,28 +249,28 @@ func (us *UserStorage) ValidatePassword(username, password string) (*models.User
 	// Update login stats
 	updates := map[string]interface{}{
 		"login_count":   gorm.Expr("login_count + ?", 1),
-		"last_login_at": time.Now(),
-		"updated_at":    time.Now(),
+		"last_login_at": utcNow(),
+		"updated_at":    utcNow(),
 	}
-	
-	if err := us.db.Model(&user).Updates(updates).Error; err != nil {
+
+	if err := us.db.WithContext(ctx).Model(&user).Updates(updates).Error; err != nil {
 		// Log the error but don't fail the login
-		fmt.Printf("Warning: failed to update login stats: %v\n", err)
+		slog.WarnContext(ctx, "failed to update login stats", "username", username, "error", err)
 	}
 
 	// Refresh user data to get updated login count
-	us.db.Where("username = ?", username).First(&user)
+	us.db.WithContext(ctx).Where("username = ?", username).First(&user)
 
 	return &user, nil
 }
 
 // GetUser retrieves a user by username
-func (us *UserStorage) GetUser(username string) (*models.User, error) {
+func (us *UserStorage) GetUser(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	
-	if err := us.db.Where("username = ?", username).First(&user).Error; err != nil {
+
+	if err := us.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -108,7 +279,7 @@ func (us *UserStorage) GetUser(username string) (*models.User, error) {
 }
 
 // ChangePassword changes a user's password
-func (us *UserStorage) ChangePassword(username, newPassword string) error {
+func (us *UserStorage) ChangePassword(ctx context.Context, username, newPassword string) error {
 	// Hash the new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
@@ -116,13 +287,14 @@ func (us *UserStorage) ChangePassword(username, newPassword string) error {
 	}
 
 	// Update password and mark as not first login
-	result := us.db.Model(&models.User{}).
+	result := us.db.WithContext(ctx).Model(&models.User{}).
 		Where("username = ?", username).
 		Updates(map[string]interface{}{
-			"password_hash":         string(hashedPassword),
+			"password_hash":        string(hashedPassword),
 			"first_login":          false,
-			"last_password_change": time.Now(),
-			"updated_at":           time.Now(),
+			"last_password_change": utcNow(),
+			"token_version":        gorm.Expr("token_version + ?", 1),
+			"updated_at":           utcNow(),
 		})
 
 	if result.Error != nil {
@@ -130,8 +302,25 @@ func (us *UserStorage) ChangePassword(username, newPassword string) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// IncrementTokenVersion invalidates all access tokens issued to a user so far
+func (us *UserStorage) IncrementTokenVersion(ctx context.Context, username string) error {
+	result := us.db.WithContext(ctx).Model(&models.User{}).
+		Where("username = ?", username).
+		Update("token_version", gorm.Expr("token_version + ?", 1))
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to update token version: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// Package tracing sets up OpenTelemetry tracing and W3C trace context
+// propagation.
+package tracing
+
+import (
+	"context"
+	"cv-backend/internal/config"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies this service in traces
+const ServiceName = "cv-backend"
+
+// Tracer returns the tracer for spans created by this service
+func Tracer() trace.Tracer {
+	return otel.Tracer(ServiceName)
+}
+
+// Setup installs the global tracer provider for cfg.Tracing.Exporter
+// (TRACING_EXPORTER) and the W3C traceparent and baggage propagators.
+// With the none exporter spans are not recorded, but incoming trace context
+// is still passed on. The returned function flushes pending spans.
+func Setup(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Tracing.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		otlp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Tracing.OTLPEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = otlp
+	case "stdout":
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		exporter = stdout
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER %q (expected none, otlp or stdout)", cfg.Tracing.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	// The frontend only passes a trace ID and records no spans itself, so the
+	// ratio also decides for traces it started. Sampling by trace ID keeps
+	// every span of a trace or none.
+	ratio := sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio)
+	sampler := sdktrace.ParentBased(ratio,
+		sdktrace.WithRemoteParentSampled(ratio),
+		sdktrace.WithRemoteParentNotSampled(ratio),
+	)
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
@@ -3,6 +3,8 @@ import { useNavigate } from 'react-router-dom';
 import { AdminProps, CVInfo, UploadResponse } from '../types';
 import { useLanguage } from '../contexts/LanguageContext';
 import { buildApiUrl, API_CONFIG } from '../config/api';
+import { apiService } from '../services/api';
+import { tracedFetch } from '../utils';
 
 const Admin: React.FC<AdminProps> = ({ isAuthenticated, onLogout }) => {
   const [selectedFile, setSelectedFile] = useState<File | null>(null);
@@ -47,7 +49,7 @@ const Admin: React.FC<AdminProps> = ({ isAuthenticated, onLogout }) => {
       const token = localStorage.getItem('cvAdminToken');
       if (!token) return;
 
-      const response = await fetch(buildApiUrl(API_CONFIG.ENDPOINTS.CV_INFO), {
+      const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.CV_INFO), {
         headers: {
           'Authorization': `Bearer ${token}`,
         },
@@ -81,7 +83,7 @@ const Admin: React.FC<AdminProps> = ({ isAuthenticated, onLogout }) => {
       const formData = new FormData();
       formData.append('cv', selectedFile);
 
-      const response = await fetch(buildApiUrl(API_CONFIG.ENDPOINTS.UPLOAD_CV), {
+      const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.UPLOAD_CV), {
         method: 'POST',
         headers: {
           'Authorization': `Bearer ${token}`,
@@ -92,7 +94,11 @@ const Admin: React.FC<AdminProps> = ({ isAuthenticated, onLogout }) => {
       const data: UploadResponse = await response.json();
 
       if (response.ok) {
-        setUploadStatus(t('admin.uploadSuccess') || 'CV uploaded successfully!');
+        if (data.unchanged) {
+          setUploadStatus(data.message || 'CV unchanged from current version');
+        } else {
+          setUploadStatus(t('admin.uploadSuccess') || 'CV uploaded successfully!');
+        }
         setSelectedFile(null);
         
         const fileInput = document.getElementById('cv-upload') as HTMLInputElement;
@@ -126,7 +132,7 @@ const Admin: React.FC<AdminProps> = ({ isAuthenticated, onLogout }) => {
         return;
       }
 
-      const response = await fetch(buildApiUrl(API_CONFIG.ENDPOINTS.DELETE_CV), {
+      const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.DELETE_CV), {
         method: 'DELETE',
         headers: {
           'Authorization': `Bearer ${token}`,
@@ -147,8 +153,6 @@ const Admin: React.FC<AdminProps> = ({ isAuthenticated, onLogout }) => {
   };
 
   const handleLogout = (): void => {
-    localStorage.removeItem('cvAdminToken');
-    localStorage.removeItem('cvAdminUsername');
     onLogout();
     navigate('/login');
   };
@@ -174,7 +178,7 @@ const Admin: React.FC<AdminProps> = ({ isAuthenticated, onLogout }) => {
         return;
       }
 
-      const response = await fetch(buildApiUrl(API_CONFIG.ENDPOINTS.CHANGE_PASSWORD), {
+      const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.CHANGE_PASSWORD), {
         method: 'PUT',
         headers: {
           'Authorization': `Bearer ${token}`,
@@ -189,6 +193,8 @@ const Admin: React.FC<AdminProps> = ({ isAuthenticated, onLogout }) => {
       const data = await response.json();
 
       if (response.ok) {
+        // The change revoked all previous tokens; keep this session with the new pair
+        apiService.storeTokens(data);
         setPasswordStatus('Password changed successfully!');
         setPasswordForm({
           currentPassword: '',
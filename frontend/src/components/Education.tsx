@@ -1,16 +1,15 @@
 import React from 'react';
 import { useLanguage } from '../contexts/LanguageContext';
 import type { Education as EducationType, Language as LanguageType, Hobby as HobbyType } from '../types/education';
-import educationData from '../data/education.json';
-import languagesData from '../data/languages.json';
-import hobbiesData from '../data/hobbies.json';
+import { useCVContent } from '../hooks/useCVContent';
 
 const Education: React.FC = () => {
   const { t, language } = useLanguage();
   
-  const education = educationData as EducationType[];
-  const languages = languagesData as LanguageType[];
-  const hobbies = hobbiesData as HobbyType[];
+  const content = useCVContent();
+  const education: EducationType[] = content.education;
+  const languages: LanguageType[] = content.languages;
+  const hobbies: HobbyType[] = content.hobbies;
 
   return (
     <section className="education" id="education">
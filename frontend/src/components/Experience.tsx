@@ -1,12 +1,12 @@
 import React from 'react';
 import { Experience } from '../types/experience';
 import { useLanguage } from '../contexts/LanguageContext';
-import experiencesData from '../data/experiences.json';
+import { useCVContent } from '../hooks/useCVContent';
 
 const ExperienceComponent: React.FC = () => {
   const { t, language } = useLanguage();
   
-  const experiences: Experience[] = experiencesData;
+  const { experiences } = useCVContent();
 
   return (
     <section className="experience" id="experience">
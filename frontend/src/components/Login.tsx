@@ -8,6 +8,9 @@ const Login: React.FC<LoginProps> = ({ onLogin }) => {
   const [credentials, setCredentials] = useState<LoginCredentials>({ username: '', password: '' });
   const [isLoading, setIsLoading] = useState<boolean>(false);
   const [error, setError] = useState<string>('');
+  // Set when the password was accepted and a two-factor code is needed
+  const [challengeToken, setChallengeToken] = useState<string>('');
+  const [code, setCode] = useState<string>('');
   const navigate = useNavigate();
 
   const handleSubmit = async (e: React.FormEvent<HTMLFormElement>): Promise<void> => {
@@ -16,9 +19,16 @@ const Login: React.FC<LoginProps> = ({ onLogin }) => {
     setError('');
 
     try {
-      const data = await apiService.login(credentials);
-      
-      localStorage.setItem(STORAGE_KEYS.ADMIN_TOKEN, data.token);
+      const data = challengeToken
+        ? await apiService.loginTwoFactor(challengeToken, code)
+        : await apiService.login(credentials);
+
+      if (data.two_factor_required && data.challenge_token) {
+        setChallengeToken(data.challenge_token);
+        return;
+      }
+
+      apiService.storeTokens(data);
       localStorage.setItem(STORAGE_KEYS.ADMIN_USERNAME, credentials.username);
       onLogin(data.token);
       navigate(ROUTES.ADMIN);
@@ -72,6 +82,25 @@ const Login: React.FC<LoginProps> = ({ onLogin }) => {
             />
           </div>
           
+          {challengeToken && (
+            <div className="form-group">
+              <label htmlFor="code">Authentication code</label>
+              <input
+                type="text"
+                id="code"
+                name="code"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                required
+                autoFocus
+                autoComplete="one-time-code"
+                placeholder="123456 or recovery code"
+                className="form-input"
+                disabled={isLoading}
+              />
+            </div>
+          )}
+
           {error && (
             <div className="error-message">
               {error}
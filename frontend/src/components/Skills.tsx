@@ -1,16 +1,15 @@
 import React from 'react';
 import { SkillCategory, Industry, Achievement } from '../types/skills';
 import { useLanguage } from '../contexts/LanguageContext';
-import skillsData from '../data/skills.json';
-import industriesData from '../data/industries.json';
-import achievementsData from '../data/achievements.json';
+import { useCVContent } from '../hooks/useCVContent';
 
 const Skills: React.FC = () => {
   const { t, language } = useLanguage();
   
-  const skillCategories = skillsData as SkillCategory[];
-  const industries = industriesData as Industry[];
-  const achievements = achievementsData as Achievement[];
+  const content = useCVContent();
+  const skillCategories: SkillCategory[] = content.skills;
+  const industries: Industry[] = content.industries;
+  const achievements: Achievement[] = content.achievements;
 
   return (
     <section className="skills" id="skills">
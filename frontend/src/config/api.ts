@@ -2,7 +2,10 @@ export const API_CONFIG = {
   BASE_URL: process.env.REACT_APP_API_URL || 'http://localhost:8080',
   ENDPOINTS: {
     LOGIN: '/api/login',
+    LOGIN_2FA: '/api/login/2fa',
     VERIFY: '/api/verify',
+    REFRESH: '/api/refresh',
+    LOGOUT: '/api/logout',
     CV_INFO: '/api/cv-info',
     UPLOAD_CV: '/api/upload-cv',
     DOWNLOAD_CV: '/api/download-cv',
@@ -10,6 +13,7 @@ export const API_CONFIG = {
     DELETE_CV: '/api/cv',
     CHANGE_PASSWORD: '/api/change-password',
     STATS: '/api/cv-stats',
+    CV_CONTENT: '/api/cv/content',
   }
 };
 
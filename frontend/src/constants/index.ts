@@ -1,6 +1,7 @@
 // Local Storage Keys
 export const STORAGE_KEYS = {
   ADMIN_TOKEN: 'cvAdminToken',
+  ADMIN_REFRESH_TOKEN: 'cvAdminRefreshToken',
   ADMIN_USERNAME: 'cvAdminUsername',
 } as const;
 
@@ -14,14 +14,17 @@ export const useAuth = (): UseAuthReturn => {
   const [isLoading, setIsLoading] = useState<boolean>(true);
 
   const logout = useCallback((): void => {
+    apiService.logout();
     localStorage.removeItem(STORAGE_KEYS.ADMIN_TOKEN);
+    localStorage.removeItem(STORAGE_KEYS.ADMIN_REFRESH_TOKEN);
     localStorage.removeItem(STORAGE_KEYS.ADMIN_USERNAME);
     setIsAuthenticated(false);
   }, []);
 
   const verifyToken = useCallback(async (token: string): Promise<void> => {
     try {
-      const isValid = await apiService.verifyToken(token);
+      // Access tokens are short-lived, so an expired one is renewed with the refresh token
+      const isValid = await apiService.verifyToken(token) || await apiService.refreshSession();
       if (isValid) {
         setIsAuthenticated(true);
       } else {
@@ -0,0 +1,70 @@
+import { useState, useEffect } from 'react';
+import { apiService } from '../services/api';
+import { CVContent } from '../types';
+import { Experience } from '../types/experience';
+import { Education, Language, Hobby } from '../types/education';
+import { SkillCategory, Industry, Achievement } from '../types/skills';
+import experiencesData from '../data/experiences.json';
+import educationData from '../data/education.json';
+import skillsData from '../data/skills.json';
+import industriesData from '../data/industries.json';
+import languagesData from '../data/languages.json';
+import hobbiesData from '../data/hobbies.json';
+import achievementsData from '../data/achievements.json';
+
+// Bundled content shown until the API responds, or if it is unreachable
+const staticContent: CVContent = {
+  experiences: experiencesData as Experience[],
+  education: educationData as Education[],
+  skills: skillsData as SkillCategory[],
+  industries: industriesData as Industry[],
+  languages: languagesData as Language[],
+  hobbies: hobbiesData as Hobby[],
+  achievements: achievementsData as Achievement[],
+};
+
+let cachedContent: CVContent | null = null;
+let pendingRequest: Promise<CVContent | null> | null = null;
+
+// Sections the backend returns empty (e.g. not imported yet) keep the bundled entries
+const mergeContent = (remote: CVContent): CVContent => {
+  const merged = { ...staticContent };
+  (Object.keys(staticContent) as (keyof CVContent)[]).forEach((section) => {
+    const entries = remote[section];
+    if (Array.isArray(entries) && entries.length > 0) {
+      (merged as Record<keyof CVContent, unknown[]>)[section] = entries;
+    }
+  });
+  return merged;
+};
+
+export const useCVContent = (): CVContent => {
+  const [content, setContent] = useState<CVContent>(cachedContent || staticContent);
+
+  useEffect(() => {
+    if (cachedContent) {
+      return;
+    }
+
+    // Share one request between all sections rendered on the page
+    if (!pendingRequest) {
+      pendingRequest = apiService.getCvContent();
+    }
+
+    let active = true;
+    pendingRequest.then((remote) => {
+      if (remote) {
+        cachedContent = mergeContent(remote);
+        if (active) {
+          setContent(cachedContent);
+        }
+      }
+    });
+
+    return () => {
+      active = false;
+    };
+  }, []);
+
+  return content;
+};
@@ -1,5 +1,7 @@
-import { LoginCredentials, LoginResponse, CVInfo, UploadResponse } from '../types';
+import { LoginCredentials, LoginResponse, CVInfo, UploadResponse, CVContent } from '../types';
 import { API_CONFIG, buildApiUrl } from '../config/api';
+import { STORAGE_KEYS } from '../constants';
+import { tracedFetch } from '../utils';
 
 class ApiService {
   private getAuthHeaders(): HeadersInit {
@@ -10,7 +12,7 @@ class ApiService {
   }
 
   async login(credentials: LoginCredentials): Promise<LoginResponse> {
-    const response = await fetch(buildApiUrl(API_CONFIG.ENDPOINTS.LOGIN), {
+    const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.LOGIN), {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
@@ -27,9 +29,79 @@ class ApiService {
     return data;
   }
 
+  async loginTwoFactor(challengeToken: string, code: string): Promise<LoginResponse> {
+    const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.LOGIN_2FA), {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ challenge_token: challengeToken, code }),
+    });
+
+    const data = await response.json();
+
+    if (!response.ok) {
+      throw new Error(data.error || 'Login failed');
+    }
+
+    return data;
+  }
+
+  // Exchanges the stored refresh token for a new token pair and stores it.
+  // Returns false if the session has ended.
+  async refreshSession(): Promise<boolean> {
+    const refreshToken = localStorage.getItem(STORAGE_KEYS.ADMIN_REFRESH_TOKEN);
+    if (!refreshToken) return false;
+
+    try {
+      const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.REFRESH), {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      });
+
+      if (!response.ok) return false;
+
+      const data: LoginResponse = await response.json();
+      this.storeTokens(data);
+      return true;
+    } catch (error) {
+      console.error('Token refresh failed:', error);
+      return false;
+    }
+  }
+
+  storeTokens(data: { token?: string; refresh_token?: string }): void {
+    if (data.token) {
+      localStorage.setItem(STORAGE_KEYS.ADMIN_TOKEN, data.token);
+    }
+    if (data.refresh_token) {
+      localStorage.setItem(STORAGE_KEYS.ADMIN_REFRESH_TOKEN, data.refresh_token);
+    }
+  }
+
+  async logout(): Promise<void> {
+    const refreshToken = localStorage.getItem(STORAGE_KEYS.ADMIN_REFRESH_TOKEN);
+    if (!refreshToken) return;
+
+    try {
+      await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.LOGOUT), {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      });
+    } catch (error) {
+      console.error('Logout failed:', error);
+    }
+  }
+
   async verifyToken(token: string): Promise<boolean> {
     try {
-      const response = await fetch(buildApiUrl(API_CONFIG.ENDPOINTS.VERIFY), {
+      const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.VERIFY), {
         headers: {
           'Authorization': `Bearer ${token}`,
         },
@@ -44,7 +116,7 @@ class ApiService {
 
   async getCvInfo(): Promise<CVInfo | null> {
     try {
-      const response = await fetch(buildApiUrl(API_CONFIG.ENDPOINTS.CV_INFO), {
+      const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.CV_INFO), {
         headers: this.getAuthHeaders(),
       });
 
@@ -70,7 +142,7 @@ class ApiService {
       headers['Authorization'] = `Bearer ${token}`;
     }
 
-    const response = await fetch(buildApiUrl(API_CONFIG.ENDPOINTS.UPLOAD_CV), {
+    const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.UPLOAD_CV), {
       method: 'POST',
       headers,
       body: formData,
@@ -85,6 +157,20 @@ class ApiService {
     return data;
   }
 
+  async getCvContent(): Promise<CVContent | null> {
+    try {
+      const response = await tracedFetch(buildApiUrl(API_CONFIG.ENDPOINTS.CV_CONTENT));
+
+      if (response.ok) {
+        return await response.json();
+      }
+      return null;
+    } catch (error) {
+      console.error('Failed to fetch CV content:', error);
+      return null;
+    }
+  }
+
   getDownloadCvUrl(): string {
     return buildApiUrl(API_CONFIG.ENDPOINTS.DOWNLOAD_CV);
   }
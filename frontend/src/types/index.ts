@@ -1,3 +1,7 @@
+import { Experience } from './experience';
+import { Education, Language, Hobby } from './education';
+import { SkillCategory, Industry, Achievement } from './skills';
+
 // Authentication types
 export interface LoginCredentials {
   username: string;
@@ -6,6 +10,10 @@ export interface LoginCredentials {
 
 export interface LoginResponse {
   token: string;
+  refresh_token?: string;
+  expires_in?: number;
+  two_factor_required?: boolean;
+  challenge_token?: string;
   error?: string;
 }
 
@@ -19,7 +27,21 @@ export interface CVInfo {
 export interface UploadResponse {
   success: boolean;
   error?: string;
+  code?: string;
   message?: string;
+  unchanged?: boolean;
+  sha256?: string;
+}
+
+// Structured CV content served by /api/cv/content
+export interface CVContent {
+  experiences: Experience[];
+  education: Education[];
+  skills: SkillCategory[];
+  industries: Industry[];
+  languages: Language[];
+  hobbies: Hobby[];
+  achievements: Achievement[];
 }
 
 // Component props
@@ -15,4 +15,23 @@ export const downloadFile = (url: string, filename: string): void => {
  */
 export const openInNewTab = (url: string): void => {
   window.open(url, '_blank', 'noopener,noreferrer');
-};
\ No newline at end of file
+};
+const randomHex = (bytes: number): string =>
+  Array.from(crypto.getRandomValues(new Uint8Array(bytes)), (b) => b.toString(16).padStart(2, '0')).join('');
+
+/**
+ * Creates a W3C traceparent header value that starts a new trace
+ */
+export const newTraceparent = (): string => `00-${randomHex(16)}-${randomHex(8)}-01`;
+
+/**
+ * fetch that sends a traceparent header, so that the backend's spans and log
+ * lines for the call share one trace ID
+ */
+export const tracedFetch = (input: RequestInfo | URL, init: RequestInit = {}): Promise<Response> => {
+  const headers = new Headers(init.headers);
+  if (!headers.has('traceparent')) {
+    headers.set('traceparent', newTraceparent());
+  }
+  return fetch(input, { ...init, headers });
+};